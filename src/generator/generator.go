@@ -0,0 +1,304 @@
+package generator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	Useful functions offered by this package:
+		- Generate: builds a graph from a named model and its Params
+		- ParseParams: parses key=value command line arguments into Params
+		- Adjacency.WriteText / WriteDIMACS / WriteFile: stream a generated graph to our text format or DIMACS
+	Graphs are built as int32 neighbor lists, so memory stays O(n + m) and no adjacency matrix is ever allocated.
+	Every model is seeded, so the same model, Params and Seed always produce the same graph.
+*/
+
+// ErrMaxDegree is returned when a model cannot be built within the requested max degree
+var ErrMaxDegree = errors.New("model exceeds max degree")
+
+// ErrBadParams is returned when a model is missing a parameter or given an invalid one
+var ErrBadParams = errors.New("bad generator parameters")
+
+// Params holds every parameter a model may use. Models ignore the ones that do not apply to them
+//		N: the number of nodes
+//		P: the edge probability for gnp
+//		D: the degree for regular
+//		M: the number of edges each new node adds for ba
+//		Rows, Cols: the dimensions for grid and torus
+//		A, B: the sizes of the two sides for bipartite
+//		Trees: the number of trees for forest
+//		MaxDegree: the degree cap every node is guaranteed to respect, or 0 for no cap
+//		Seed: the seed for the random number generator
+//		Name, Description: the header lines of the output file, generated from the model if empty
+type Params struct {
+	N           int
+	P           float64
+	D           int
+	M           int
+	Rows        int
+	Cols        int
+	A           int
+	B           int
+	Trees       int
+	MaxDegree   int
+	Seed        int64
+	Name        string
+	Description string
+}
+
+// model builds a graph from Params using the given random number generator
+type model func(params Params, rng *rand.Rand) (*Adjacency, error)
+
+// models maps the names accepted by Generate to their builders
+var models = map[string]model{
+	"gnp":       erdosRenyi,
+	"regular":   randomRegular,
+	"ring":      ring,
+	"path":      path,
+	"grid":      grid,
+	"torus":     torus,
+	"complete":  complete,
+	"bipartite": completeBipartite,
+	"tree":      randomTree,
+	"forest":    randomForest,
+	"ba":        barabasiAlbert,
+}
+
+// Models returns the sorted names of every model Generate accepts
+func Models() []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Generate builds a graph from the named model
+func Generate(modelName string, params Params) (*Adjacency, error) {
+	build, ok := models[modelName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown model %s, expected one of %s", ErrBadParams, modelName, strings.Join(Models(), ", "))
+	}
+	adj, err := build(params, rand.New(rand.NewSource(params.Seed)))
+	if err != nil {
+		return nil, err
+	}
+	adj.Name = params.Name
+	if adj.Name == "" {
+		adj.Name = fmt.Sprintf("Graph_%s_N%d_D%d_S%d", modelName, len(adj.Neighbors), adj.MaxDegree(), params.Seed)
+	}
+	adj.Description = params.Description
+	if adj.Description == "" {
+		adj.Description = fmt.Sprintf("A %s graph with %d nodes, %d edges and a max degree of %d (seed %d)",
+			modelName, len(adj.Neighbors), adj.NumEdges(), adj.MaxDegree(), params.Seed)
+	}
+	return adj, nil
+}
+
+// ParseParams parses arguments of the form key=value, e.g. n=1000 p=0.01 maxdeg=10 seed=42
+func ParseParams(args []string) (Params, error) {
+	var params Params
+	for _, arg := range args {
+		splitted := strings.SplitN(arg, "=", 2)
+		if len(splitted) != 2 {
+			return params, fmt.Errorf("%w: expected key=value, got %s", ErrBadParams, arg)
+		}
+		key, val := splitted[0], splitted[1]
+		var err error
+		switch key {
+		case "n":
+			params.N, err = strconv.Atoi(val)
+		case "p":
+			params.P, err = strconv.ParseFloat(val, 64)
+		case "d":
+			params.D, err = strconv.Atoi(val)
+		case "m":
+			params.M, err = strconv.Atoi(val)
+		case "rows":
+			params.Rows, err = strconv.Atoi(val)
+		case "cols":
+			params.Cols, err = strconv.Atoi(val)
+		case "a":
+			params.A, err = strconv.Atoi(val)
+		case "b":
+			params.B, err = strconv.Atoi(val)
+		case "trees":
+			params.Trees, err = strconv.Atoi(val)
+		case "maxdeg":
+			params.MaxDegree, err = strconv.Atoi(val)
+		case "seed":
+			params.Seed, err = strconv.ParseInt(val, 10, 64)
+		case "name":
+			params.Name = val
+		case "desc":
+			params.Description = val
+		default:
+			return params, fmt.Errorf("%w: unknown parameter %s", ErrBadParams, key)
+		}
+		if err != nil {
+			return params, fmt.Errorf("%w: %s=%s: %s", ErrBadParams, key, val, err)
+		}
+	}
+	return params, nil
+}
+
+// Adjacency is an undirected graph under construction, stored as lists of neighbor indices
+//		Name, Description: the header lines written to the output file
+//		Neighbors: the neighbor indices of every node
+type Adjacency struct {
+	Name        string
+	Description string
+	Neighbors   [][]int32
+	degreeCap   int
+}
+
+// newAdjacency creates an edgeless graph on n nodes whose degrees may not exceed degreeCap (0 for no cap)
+func newAdjacency(n int, degreeCap int) *Adjacency {
+	if degreeCap <= 0 {
+		degreeCap = n
+	}
+	return &Adjacency{Neighbors: make([][]int32, n), degreeCap: degreeCap}
+}
+
+// hasCapacity returns whether node u can take another edge without exceeding the degree cap
+func (a *Adjacency) hasCapacity(u int) bool {
+	return len(a.Neighbors[u]) < a.degreeCap
+}
+
+// hasEdge checks whether u and v are neighbors by scanning the shorter neighbor list
+func (a *Adjacency) hasEdge(u int, v int) bool {
+	if len(a.Neighbors[u]) > len(a.Neighbors[v]) {
+		u, v = v, u
+	}
+	for _, w := range a.Neighbors[u] {
+		if int(w) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// addEdge adds the edge u-v unless it is a self-loop, already present, or would exceed the degree cap.
+// It returns whether the edge was added
+func (a *Adjacency) addEdge(u int, v int) bool {
+	if u == v || !a.hasCapacity(u) || !a.hasCapacity(v) || a.hasEdge(u, v) {
+		return false
+	}
+	a.addEdgeUnchecked(u, v)
+	return true
+}
+
+// addEdgeUnchecked adds the edge u-v for models that can only ever produce new, valid edges
+func (a *Adjacency) addEdgeUnchecked(u int, v int) {
+	a.Neighbors[u] = append(a.Neighbors[u], int32(v))
+	a.Neighbors[v] = append(a.Neighbors[v], int32(u))
+}
+
+// removeEdge removes the edge u-v, which must be present
+func (a *Adjacency) removeEdge(u int, v int) {
+	a.Neighbors[u] = removeNeighbor(a.Neighbors[u], v)
+	a.Neighbors[v] = removeNeighbor(a.Neighbors[v], u)
+}
+
+// removeNeighbor removes v from a neighbor list without preserving order
+func removeNeighbor(list []int32, v int) []int32 {
+	for i, w := range list {
+		if int(w) == v {
+			list[i] = list[len(list)-1]
+			return list[:len(list)-1]
+		}
+	}
+	return list
+}
+
+// MaxDegree returns the largest degree of any node
+func (a *Adjacency) MaxDegree() int {
+	res := 0
+	for _, list := range a.Neighbors {
+		if len(list) > res {
+			res = len(list)
+		}
+	}
+	return res
+}
+
+// NumEdges returns the number of undirected edges
+func (a *Adjacency) NumEdges() int {
+	total := 0
+	for _, list := range a.Neighbors {
+		total += len(list)
+	}
+	return total / 2
+}
+
+// WriteText streams the graph in our Name:Neighbor1,Neighbor2 text format, naming every node by its index
+func (a *Adjacency) WriteText(w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 1<<20)
+	fmt.Fprintf(bw, "%s\n%s\n%d\n", a.Name, a.Description, a.MaxDegree())
+	line := make([]byte, 0, 256)
+	for u, list := range a.Neighbors {
+		line = strconv.AppendInt(line[:0], int64(u), 10)
+		line = append(line, ':')
+		for i, v := range list {
+			if i > 0 {
+				line = append(line, ',')
+			}
+			line = strconv.AppendInt(line, int64(v), 10)
+		}
+		line = append(line, '\n')
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteDIMACS streams the graph in DIMACS .col format, numbering nodes from 1
+func (a *Adjacency) WriteDIMACS(w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 1<<20)
+	fmt.Fprintf(bw, "c %s\nc %s\np edge %d %d\n", a.Name, a.Description, len(a.Neighbors), a.NumEdges())
+	line := make([]byte, 0, 32)
+	for u, list := range a.Neighbors {
+		for _, v := range list {
+			if int(v) > u {
+				line = append(line[:0], 'e', ' ')
+				line = strconv.AppendInt(line, int64(u+1), 10)
+				line = append(line, ' ')
+				line = strconv.AppendInt(line, int64(v+1), 10)
+				line = append(line, '\n')
+				if _, err := bw.Write(line); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes the graph to fileName, in DIMACS format if it ends in .col and in our text format otherwise
+func (a *Adjacency) WriteFile(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(fileName), ".col") {
+		err = a.WriteDIMACS(f)
+	} else {
+		err = a.WriteText(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,356 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+/*
+	The graph models offered by Generate. Every model respects Params.MaxDegree:
+	random models skip any edge that would exceed it, and fixed models return ErrMaxDegree if their shape needs more.
+*/
+
+// checkFixedDegree returns ErrMaxDegree if a model whose nodes need degree nodes cannot fit under maxDegree
+func checkFixedDegree(modelName string, degree int, maxDegree int) error {
+	if maxDegree > 0 && degree > maxDegree {
+		return fmt.Errorf("%w: %s needs degree %d but maxdeg is %d", ErrMaxDegree, modelName, degree, maxDegree)
+	}
+	return nil
+}
+
+// checkPositive returns ErrBadParams unless every named value is at least 1
+func checkPositive(modelName string, names []string, values ...int) error {
+	for i, val := range values {
+		if val < 1 {
+			return fmt.Errorf("%w: %s needs %s >= 1", ErrBadParams, modelName, names[i])
+		}
+	}
+	return nil
+}
+
+// erdosRenyi builds G(n,p), where every pair of nodes is an edge with probability p.
+// Pairs are visited with geometric skips (Batagelj and Brandes), so the run time is O(n + m) instead of O(n^2)
+func erdosRenyi(params Params, rng *rand.Rand) (*Adjacency, error) {
+	n, p := params.N, params.P
+	if err := checkPositive("gnp", []string{"n"}, n); err != nil {
+		return nil, err
+	}
+	if p < 0 || p > 1 {
+		return nil, fmt.Errorf("%w: gnp needs 0 <= p <= 1", ErrBadParams)
+	}
+	adj := newAdjacency(n, params.MaxDegree)
+	if p == 0 {
+		return adj, nil
+	}
+	logQ := math.Log(1 - p)
+	v, w := 1, -1
+	for v < n {
+		skip := 0
+		if p < 1 {
+			skip = int(math.Floor(math.Log(1-rng.Float64()) / logQ))
+		}
+		w += 1 + skip
+		for w >= v && v < n {
+			w -= v
+			v++
+		}
+		if v < n && adj.hasCapacity(v) && adj.hasCapacity(w) {
+			adj.addEdgeUnchecked(v, w)
+		}
+	}
+	return adj, nil
+}
+
+// regularRestarts is how many fresh pairings randomRegular draws before giving up, since dense pairings may not be repairable
+const regularRestarts = 20
+
+// randomRegular builds a random d-regular graph with the pairing model.
+// Self-loops and repeated edges from the pairing are repaired by switching them with random existing edges,
+// and a pairing that cannot be repaired is drawn again. The only (n-1)-regular graph is K_n, which is built directly
+func randomRegular(params Params, rng *rand.Rand) (*Adjacency, error) {
+	n, d := params.N, params.D
+	if err := checkPositive("regular", []string{"n"}, n); err != nil {
+		return nil, err
+	}
+	if d < 0 || d >= n || n*d%2 != 0 {
+		return nil, fmt.Errorf("%w: regular needs 0 <= d < n and n*d even", ErrBadParams)
+	}
+	if err := checkFixedDegree("regular", d, params.MaxDegree); err != nil {
+		return nil, err
+	}
+	if d == n-1 {
+		return complete(params, rng)
+	}
+	for restart := 0; restart < regularRestarts; restart++ {
+		if adj := pairRegular(n, d, rng); adj != nil {
+			return adj, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: could not repair the pairing for regular n=%d d=%d", ErrBadParams, n, d)
+}
+
+// pairRegular draws one pairing of n*d stubs and repairs it into a d-regular graph, or returns nil if the repair gives up
+func pairRegular(n int, d int, rng *rand.Rand) *Adjacency {
+	adj := newAdjacency(n, d)
+	for i := range adj.Neighbors {
+		adj.Neighbors[i] = make([]int32, 0, d)
+	}
+
+	stubs := make([]int32, n*d)
+	for i := range stubs {
+		stubs[i] = int32(i / d)
+	}
+	rng.Shuffle(len(stubs), func(i, j int) { stubs[i], stubs[j] = stubs[j], stubs[i] })
+
+	edges := make([][2]int32, 0, len(stubs)/2)
+	var bad [][2]int32
+	for i := 0; i < len(stubs); i += 2 {
+		u, v := int(stubs[i]), int(stubs[i+1])
+		if u != v && !adj.hasEdge(u, v) {
+			adj.addEdgeUnchecked(u, v)
+			edges = append(edges, [2]int32{stubs[i], stubs[i+1]})
+		} else {
+			bad = append(bad, [2]int32{stubs[i], stubs[i+1]})
+		}
+	}
+
+	// Replace each bad pair u-v and a random edge x-y with u-x and v-y
+	maxAttempts := 100 * (len(bad) + 1) * (d + 1)
+	for attempts := 0; len(bad) > 0; attempts++ {
+		if attempts >= maxAttempts || len(edges) == 0 {
+			return nil
+		}
+		u, v := int(bad[len(bad)-1][0]), int(bad[len(bad)-1][1])
+		k := rng.Intn(len(edges))
+		x, y := int(edges[k][0]), int(edges[k][1])
+		if rng.Intn(2) == 0 {
+			x, y = y, x
+		}
+		if u == x || u == y || v == x || v == y || adj.hasEdge(u, x) || adj.hasEdge(v, y) {
+			continue
+		}
+		adj.removeEdge(x, y)
+		adj.addEdgeUnchecked(u, x)
+		adj.addEdgeUnchecked(v, y)
+		edges[k] = [2]int32{int32(u), int32(x)}
+		edges = append(edges, [2]int32{int32(v), int32(y)})
+		bad = bad[:len(bad)-1]
+	}
+	return adj
+}
+
+// path builds the path 0-1-...-(n-1)
+func path(params Params, rng *rand.Rand) (*Adjacency, error) {
+	n := params.N
+	if err := checkPositive("path", []string{"n"}, n); err != nil {
+		return nil, err
+	}
+	if n > 2 {
+		if err := checkFixedDegree("path", 2, params.MaxDegree); err != nil {
+			return nil, err
+		}
+	}
+	adj := newAdjacency(n, 0)
+	for i := 1; i < n; i++ {
+		adj.addEdgeUnchecked(i-1, i)
+	}
+	return adj, nil
+}
+
+// ring builds the cycle 0-1-...-(n-1)-0
+func ring(params Params, rng *rand.Rand) (*Adjacency, error) {
+	if params.N < 3 {
+		return nil, fmt.Errorf("%w: ring needs n >= 3", ErrBadParams)
+	}
+	adj, err := path(params, rng)
+	if err != nil {
+		return nil, err
+	}
+	adj.addEdgeUnchecked(params.N-1, 0)
+	return adj, nil
+}
+
+// grid builds a rows x cols grid, where node r*cols+c is joined to its horizontal and vertical neighbors
+func grid(params Params, rng *rand.Rand) (*Adjacency, error) {
+	return buildGrid("grid", params, false)
+}
+
+// torus builds a rows x cols grid whose rows and columns wrap around
+func torus(params Params, rng *rand.Rand) (*Adjacency, error) {
+	return buildGrid("torus", params, true)
+}
+
+// buildGrid builds the grid and torus models. Wrap-around edges that would repeat an edge of a short row or column are skipped
+func buildGrid(modelName string, params Params, wrap bool) (*Adjacency, error) {
+	rows, cols := params.Rows, params.Cols
+	if err := checkPositive(modelName, []string{"rows", "cols"}, rows, cols); err != nil {
+		return nil, err
+	}
+	adj := newAdjacency(rows*cols, 0)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			u := r*cols + c
+			if c+1 < cols {
+				adj.addEdgeUnchecked(u, u+1)
+			} else if wrap && cols > 2 {
+				adj.addEdgeUnchecked(u, r*cols)
+			}
+			if r+1 < rows {
+				adj.addEdgeUnchecked(u, u+cols)
+			} else if wrap && rows > 2 {
+				adj.addEdgeUnchecked(u, c)
+			}
+		}
+	}
+	if err := checkFixedDegree(modelName, adj.MaxDegree(), params.MaxDegree); err != nil {
+		return nil, err
+	}
+	return adj, nil
+}
+
+// complete builds the complete graph K_n
+func complete(params Params, rng *rand.Rand) (*Adjacency, error) {
+	n := params.N
+	if err := checkPositive("complete", []string{"n"}, n); err != nil {
+		return nil, err
+	}
+	if err := checkFixedDegree("complete", n-1, params.MaxDegree); err != nil {
+		return nil, err
+	}
+	adj := newAdjacency(n, 0)
+	for u := range adj.Neighbors {
+		adj.Neighbors[u] = make([]int32, 0, n-1)
+	}
+	for u := 0; u < n; u++ {
+		for v := u + 1; v < n; v++ {
+			adj.addEdgeUnchecked(u, v)
+		}
+	}
+	return adj, nil
+}
+
+// completeBipartite builds K_{a,b}, with nodes 0..a-1 on one side and a..a+b-1 on the other
+func completeBipartite(params Params, rng *rand.Rand) (*Adjacency, error) {
+	a, b := params.A, params.B
+	if err := checkPositive("bipartite", []string{"a", "b"}, a, b); err != nil {
+		return nil, err
+	}
+	degree := a
+	if b > a {
+		degree = b
+	}
+	if err := checkFixedDegree("bipartite", degree, params.MaxDegree); err != nil {
+		return nil, err
+	}
+	adj := newAdjacency(a+b, 0)
+	for u := 0; u < a; u++ {
+		for v := a; v < a+b; v++ {
+			adj.addEdgeUnchecked(u, v)
+		}
+	}
+	return adj, nil
+}
+
+// randomTree builds a random recursive tree on n nodes
+func randomTree(params Params, rng *rand.Rand) (*Adjacency, error) {
+	params.Trees = 1
+	return randomForest(params, rng)
+}
+
+// randomForest builds a forest of the given number of random recursive trees.
+// Nodes 0..trees-1 are the roots, and every later node attaches to a uniformly random earlier node with room under the max degree
+func randomForest(params Params, rng *rand.Rand) (*Adjacency, error) {
+	n, trees := params.N, params.Trees
+	if err := checkPositive("forest", []string{"n", "trees"}, n, trees); err != nil {
+		return nil, err
+	}
+	if trees > n {
+		return nil, fmt.Errorf("%w: forest needs trees <= n", ErrBadParams)
+	}
+	if params.MaxDegree == 1 && n > 2*trees {
+		return nil, fmt.Errorf("%w: a forest of %d trees on %d nodes needs maxdeg >= 2", ErrMaxDegree, trees, n)
+	}
+	adj := newAdjacency(n, params.MaxDegree)
+
+	// available holds every node that can still take a child
+	available := make([]int32, 0, n)
+	for i := 0; i < trees; i++ {
+		available = append(available, int32(i))
+	}
+	for v := trees; v < n; v++ {
+		k := rng.Intn(len(available))
+		u := int(available[k])
+		adj.addEdgeUnchecked(u, v)
+		if !adj.hasCapacity(u) {
+			available[k] = available[len(available)-1]
+			available = available[:len(available)-1]
+		}
+		if adj.hasCapacity(v) {
+			available = append(available, int32(v))
+		}
+	}
+	return adj, nil
+}
+
+// barabasiAlbert builds a preferential attachment graph. It starts from a complete graph on m+1 nodes,
+// then every new node joins m distinct earlier nodes chosen with probability proportional to their degree.
+// Nodes at the max degree are never chosen, so a new node may get fewer than m edges when few nodes have room left
+func barabasiAlbert(params Params, rng *rand.Rand) (*Adjacency, error) {
+	n, m := params.N, params.M
+	if err := checkPositive("ba", []string{"n", "m"}, n, m); err != nil {
+		return nil, err
+	}
+	if m >= n {
+		return nil, fmt.Errorf("%w: ba needs m < n", ErrBadParams)
+	}
+	if err := checkFixedDegree("ba", m, params.MaxDegree); err != nil {
+		return nil, err
+	}
+	adj := newAdjacency(n, params.MaxDegree)
+
+	// endpoints holds every node once per incident edge, so a uniform pick from it is a degree-proportional pick
+	endpoints := make([]int32, 0, 2*m*n)
+	for u := 0; u <= m; u++ {
+		for v := u + 1; v <= m; v++ {
+			if adj.addEdge(u, v) {
+				endpoints = append(endpoints, int32(u), int32(v))
+			}
+		}
+	}
+
+	targets := make([]int, 0, m)
+	for v := m + 1; v < n; v++ {
+		targets = targets[:0]
+		for tries := 0; len(targets) < m && tries < 20*m; tries++ {
+			u := int(endpoints[rng.Intn(len(endpoints))])
+			if !adj.hasCapacity(u) || containsInt(targets, u) {
+				continue
+			}
+			targets = append(targets, u)
+		}
+		// Once the high degree nodes are full, fall back to uniform picks so new nodes still attach
+		for tries := 0; len(targets) < m && tries < 20*m; tries++ {
+			u := rng.Intn(v)
+			if !adj.hasCapacity(u) || containsInt(targets, u) {
+				continue
+			}
+			targets = append(targets, u)
+		}
+		for _, u := range targets {
+			adj.addEdgeUnchecked(u, v)
+			endpoints = append(endpoints, int32(u), int32(v))
+		}
+	}
+	return adj, nil
+}
+
+// containsInt returns whether val is in list
+func containsInt(list []int, val int) bool {
+	for _, x := range list {
+		if x == val {
+			return true
+		}
+	}
+	return false
+}
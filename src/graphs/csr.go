@@ -0,0 +1,102 @@
+package graphs
+
+/*
+	Compressed sparse row graphs
+		- CSR: a Graph stored as flat int32 arrays instead of Nodes and pointers
+		- ToCSR: converts a Graph to a CSR
+		- CSR.ToGraph: converts a CSR back to a Graph
+		- CSR.Copy: copies a CSR's colors, sharing the structure no algorithm changes
+	A CSR has no per-node allocations, so copying one for each repetition of a test costs a single copy of its colors.
+*/
+
+// CSR is a Graph in compressed sparse row form. Node v's neighbors are Adj[Offsets[v]:Offsets[v+1]].
+// Node v is the Node at position v of the Graph it came from, which parsing guarantees is its Ind.
+// Offsets and Adj are int32 to halve their size, so a CSR holds up to 2^31-1 directed edges
+//		Name, Description, MaxDegree: as in Graph
+//		Names: the name of every node
+//		Offsets: where each node's neighbors start in Adj, with one more entry than there are nodes
+//		Adj: the neighbors of every node, back to back
+//		Colors: the color of every node. Algorithms run on a CSR change only Colors
+type CSR struct {
+	Name string
+	Description string
+	MaxDegree int
+	Names []string
+	Offsets []int32
+	Adj []int32
+	Colors []int
+}
+
+// ToCSR converts a Graph to a CSR, keeping its node order and each node's neighbor order
+func ToCSR(gr *Graph) *CSR {
+	position := nodePositions(gr)
+	numEdges := 0
+	for _, node := range gr.Nodes {
+		numEdges += len(node.Neighbors)
+	}
+	c := &CSR{
+		Name: gr.Name,
+		Description: gr.Description,
+		MaxDegree: gr.MaxDegree,
+		Names: make([]string, len(gr.Nodes)),
+		Offsets: make([]int32, len(gr.Nodes)+1),
+		Adj: make([]int32, 0, numEdges),
+		Colors: make([]int, len(gr.Nodes)),
+	}
+	for i, node := range gr.Nodes {
+		c.Names[i] = node.Name
+		c.Colors[i] = node.Color
+		for _, neighbor := range node.Neighbors {
+			c.Adj = append(c.Adj, int32(position(neighbor)))
+		}
+		c.Offsets[i+1] = int32(len(c.Adj))
+	}
+	return c
+}
+
+// NumNodes returns the number of nodes
+func (c *CSR) NumNodes() int {
+	return len(c.Colors)
+}
+
+// Neighbors returns the neighbors of node v. The slice belongs to the CSR and must not be changed
+func (c *CSR) Neighbors(v int) []int32 {
+	return c.Adj[c.Offsets[v]:c.Offsets[v+1]]
+}
+
+// Degree returns the number of neighbors of node v
+func (c *CSR) Degree(v int) int {
+	return int(c.Offsets[v+1] - c.Offsets[v])
+}
+
+// Copy returns a CSR with its own Colors, sharing Names, Offsets and Adj with c
+func (c *CSR) Copy() *CSR {
+	copied := *c
+	copied.Colors = make([]int, len(c.Colors))
+	copy(copied.Colors, c.Colors)
+	return &copied
+}
+
+// ToGraph converts a CSR to a Graph whose Nodes have Ind equal to their position
+func (c *CSR) ToGraph() Graph {
+	nodes := make([]Node, c.NumNodes())
+	nodeList := make([]*Node, c.NumNodes())
+	for v := range nodes {
+		nodes[v] = Node{Name: c.Names[v], Ind: v, Color: c.Colors[v]}
+		nodeList[v] = &nodes[v]
+	}
+	adj := make([]*Node, len(c.Adj))
+	for i, u := range c.Adj {
+		adj[i] = nodeList[u]
+	}
+	for v := range nodes {
+		start, end := c.Offsets[v], c.Offsets[v+1]
+		nodes[v].Neighbors = adj[start:end:end]
+	}
+	return Graph{
+		Name: c.Name,
+		Description: c.Description,
+		MaxDegree: c.MaxDegree,
+		Nodes: nodeList,
+	}
+}
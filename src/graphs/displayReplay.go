@@ -0,0 +1,174 @@
+package graphs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"io"
+	"os"
+	"time"
+)
+
+/*
+	Round by round replay of a color reduction
+		- Snapshot: the coloring of a graph after a single round, as recorded by a reduction
+		- GenerateHTMLForReplay: renders a list of Snapshots as one graph whose timeline slider steps through them
+	Nodes keep their place on a circle in every frame so only their colors change, and each color value always maps to the same hue.
+*/
+
+// Snapshot is the coloring of a graph after a single round
+//		Round: the number of LOCAL-model rounds run when the snapshot was taken
+//		Phase: the part of the algorithm that produced it, e.g. "cv forest 0", "shift-down forest 0" or "unify"
+//		Colors: the color of every node, indexed by Ind. A color of -1 means the node is uncolored or not part of this frame
+//		Edges: the pairs of Ind to draw, or nil to draw every edge of the graph, e.g. only the edges of one forest
+type Snapshot struct {
+	Round  int
+	Phase  string
+	Colors []int
+	Edges  [][2]int
+}
+
+// ColorsOf copies the current color of every node of a graph, indexed by Ind
+func ColorsOf(gr *Graph) []int {
+	colors := make([]int, len(gr.Nodes))
+	for _, node := range gr.Nodes {
+		colors[node.Ind] = node.Color
+	}
+	return colors
+}
+
+// replayColor returns a stable CSS color for a color value, spreading hues by the golden angle so nearby values look distinct
+func replayColor(color int) string {
+	if color < 0 {
+		return "#cccccc"
+	}
+	hue := (color * 137) % 360
+	return fmt.Sprintf("hsl(%d, 70%%, 50%%)", hue)
+}
+
+// generateReplayNodes generates the nodes of a single frame, labelled with their name and colored by their color value
+func generateReplayNodes(gr *Graph, colors []int) []opts.GraphNode {
+	nodes := make([]opts.GraphNode, 0, len(gr.Nodes))
+	for _, x := range gr.Nodes {
+		color := -1
+		if x.Ind < len(colors) {
+			color = colors[x.Ind]
+		}
+		nodes = append(nodes,
+			opts.GraphNode{
+				Name:      x.Name,
+				Value:     float32(color),
+				ItemStyle: &opts.ItemStyle{Color: replayColor(color)},
+			})
+	}
+	return nodes
+}
+
+// generateReplayEdges generates the edges of a single frame, drawing each undirected edge once
+func generateReplayEdges(gr *Graph, edges [][2]int) []opts.GraphLink {
+	links := make([]opts.GraphLink, 0)
+	if edges == nil {
+		for _, x := range gr.Nodes {
+			for _, neighbor := range x.Neighbors {
+				if x.Ind < neighbor.Ind {
+					links = append(links, opts.GraphLink{Source: x.Name, Target: neighbor.Name})
+				}
+			}
+		}
+		return links
+	}
+	for _, edge := range edges {
+		links = append(links, opts.GraphLink{Source: gr.Nodes[edge[0]].Name, Target: gr.Nodes[edge[1]].Name})
+	}
+	return links
+}
+
+// generateReplayGraph generates a graph chart showing the first Snapshot, with a timeline to step through the rest.
+// The timeline is labelled by frame, since several frames may share a round, e.g. the same CV iteration on different forests
+func generateReplayGraph(gr *Graph, snapshots []Snapshot, testName string) *charts.Graph {
+	graph := charts.NewGraph()
+	graph.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			PageTitle: testName + " Replay",
+			ChartID:   "replay",
+			Width:     "1000px",
+			Height:    "700px",
+		}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    fmt.Sprintf("Replay of %s", testName),
+			Subtitle: frameTitle(snapshots[0]),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: true}),
+	)
+	graph.AddSeries("graph", generateReplayNodes(gr, snapshots[0].Colors), generateReplayEdges(gr, snapshots[0].Edges)).
+		SetSeriesOptions(
+			charts.WithGraphChartOpts(
+				opts.GraphChart{
+					Layout: "circular",
+					Roam:   true,
+				}),
+			charts.WithLabelOpts(
+				opts.Label{
+					Show:     true,
+					Position: "right",
+				}),
+		)
+
+	// go-echarts has no timeline component, so the frames are attached to the rendered option in JavaScript
+	labels := make([]string, len(snapshots))
+	frames := make([]map[string]interface{}, len(snapshots))
+	for i, snap := range snapshots {
+		labels[i] = fmt.Sprintf("%d", i)
+		frames[i] = map[string]interface{}{
+			"title": map[string]interface{}{"subtext": frameTitle(snap)},
+			"series": []map[string]interface{}{{
+				"data":  generateReplayNodes(gr, snap.Colors),
+				"links": generateReplayEdges(gr, snap.Edges),
+			}},
+		}
+	}
+	labelsJSON, _ := json.Marshal(labels)
+	framesJSON, _ := json.Marshal(frames)
+	id := "replay"
+	graph.AddJSFuncs(fmt.Sprintf(`
+	option_%s.timeline = {axisType: "category", autoPlay: false, playInterval: 800, loop: false, bottom: 0, data: %s};
+	goecharts_%s.setOption({baseOption: option_%s, options: %s}, true);`,
+		id, labelsJSON, id, id, framesJSON))
+	return graph
+}
+
+// frameTitle describes a Snapshot for the subtitle of its frame
+func frameTitle(snap Snapshot) string {
+	numColors := 0
+	seen := make(map[int]bool)
+	for _, color := range snap.Colors {
+		if color >= 0 && !seen[color] {
+			seen[color] = true
+			numColors++
+		}
+	}
+	return fmt.Sprintf("Round %d: %s (%d colors)", snap.Round, snap.Phase, numColors)
+}
+
+// GenerateHTMLForReplay is a
+// Method that renders every Snapshot of a reduction on a graph as a single chart with a timeline slider.
+func GenerateHTMLForReplay(gr *Graph, snapshots []Snapshot, testName string) {
+	if len(snapshots) == 0 {
+		return
+	}
+	fmt.Printf("Generating replay html for %s with %d frames...\n", testName, len(snapshots))
+	page := components.NewPage()
+	page.AddCharts(
+		generateReplayGraph(gr, snapshots, testName),
+	)
+	now := time.Now()
+	path := fmt.Sprintf("../html/%s_%d-%d-%d-replay.html", testName, now.Hour(), now.Minute(), now.Second())
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	page.Render(io.MultiWriter(f))
+	fmt.Printf("Done generating html.\t%s\n", path)
+}
@@ -6,29 +6,63 @@ import (
 	"github.com/go-echarts/go-echarts/v2/components"
 	"github.com/go-echarts/go-echarts/v2/opts"
 	"io"
+	"math"
 	"os"
+	"sort"
 	"time"
 )
 
+// DataPoint is a struct holding the results of one algorithm across every test in a test file
+//		AlgoName: the display name of the algorithm, as registered in reductions
+//		Rounds, Messages, MaxNodeMessages: the LOCAL-model cost of each test, independent of goroutine scheduling
+//		Bits, CongestRounds, CongestCompliant: the CONGEST-model bandwidth of each test
+//		TimeElapsed: the mean runtime of each test in nanoseconds over its Repetitions
+//		TimeMin, TimeMedian, TimeStdDev: the spread of each test's measured runtimes in nanoseconds
+//		TimeCILow, TimeCIHigh: the 95% confidence interval for each test's mean runtime, drawn as a band in the runtime chart
+//		Status: each test's VerifyReport status, one of StatusSafe, StatusTooManyColors or StatusUnsafe
+//		Conflicts: the number of same-colored edges in each test's output
+//		Workers: the number of worker goroutines each test's algorithm was allowed
+//		Speedup, Efficiency: each test's speedup over a single worker and that speedup per worker, or 0 outside a pool size sweep
+//		Seeds: the seed each test's randomized choices were drawn from, which the option seed= replays
+//		InitColors, InitMaxColor: the number of distinct colors in each test's initial coloring, and the largest of them
+//		KWPhases: the number of Kuhn-Wattenhofer merge phases each test ran, or 0 for algorithms without them
+//		CVIterations: the number of Cole-Vishkin iterations each test took to reach 6 colors, or 0 for algorithms without them
+//		LogStar: log* of the number of colors below each test's largest initial color, which CVIterations is checked against
+//		KWRoundBound: the (MaxDegree+1)*phases bound on those phases' rounds from the colors the first phase started with, or 0 without them
 type DataPoint struct {
+	AlgoName string
 	Names []string
 	NumNodes []int
 	TimeElapsed []int
+	Repetitions []int
+	TimeMin []int
+	TimeMedian []int
+	TimeStdDev []int
+	TimeCILow []int
+	TimeCIHigh []int
 	NumberColors []int
 	MaxDegree []int //Added by Tyler, no implentation on visualization side yet
 	IsSafe []bool
+	Status []string
+	Conflicts []int
+	Rounds []int
+	Messages []int
+	MaxNodeMessages []int
+	Bits []int
+	CongestRounds []int
+	CongestCompliant []bool
+	Workers []int
+	Speedup []float64
+	Efficiency []float64
+	Seeds []int64
+	InitColors []int
+	InitMaxColor []int
+	KWPhases []int
+	KWRoundBound []int
+	CVIterations []int
+	LogStar []int
 }
 
-var (
-	// algoMap is a map that maps algorithm number to its name.
-	algoMap = map[int]string{
-		0 : "Naive",
-		1 : "KW",
-		2 : "Cole-Vishkin",
-		3 : "Distributed Largest-First",
-	}
-)
-
 // generateLineData is a method that generates data points for the line graph.
 func generateLineData(data []int) []opts.LineData {
 	items := make([]opts.LineData, 0)
@@ -38,6 +72,15 @@ func generateLineData(data []int) []opts.LineData {
 	return items
 }
 
+// generateFloatLineData is a method that generates data points for the line graph from ratios, rounded to two decimals.
+func generateFloatLineData(data []float64) []opts.LineData {
+	items := make([]opts.LineData, 0)
+	for i := 0; i < len(data); i++ {
+		items = append(items, opts.LineData{Value: math.Round(data[i]*100) / 100})
+	}
+	return items
+}
+
 // generateLineChart is a method that generates a new line chart based on the the map of algorithms to DataPoint objects.
 // In this case we are generating a line chart that graphs Runtime on the Y axis and NumNodes on the X axis.
 func generateLineChart(data map[int]DataPoint) *charts.Line{
@@ -57,12 +100,12 @@ func generateLineChart(data map[int]DataPoint) *charts.Line{
 func generateNodeLineChart(data map[int]DataPoint) *charts.Line {
 	lineGraph := charts.NewLine()
 	categories := make([]*opts.GraphCategory, 0)
-	numAlgos := len(data)
+	algoIds := sortedAlgoIds(data)
 
-	for i := 0; i < numAlgos; i++ {
+	for _, i := range algoIds {
 		categories = append(categories,
 			&opts.GraphCategory{
-				Name: fmt.Sprintf("%s", algoMap[i]),
+				Name: data[i].AlgoName,
 				Label: &opts.Label{
 					Show:     true,
 					Position: "right",
@@ -92,47 +135,29 @@ func generateNodeLineChart(data map[int]DataPoint) *charts.Line {
 	// In this case we are assuming the Number of Nodes is what changes in each new run of the test,
 	// we are assuming MaxDegree is the same for all runs of the tests.
 	var algInd int
-	for i, v := range data {
-		if v.NumNodes != nil {
+	for _, i := range algoIds {
+		if data[i].NumNodes != nil {
 			algInd = i
 			break
 		}
 	}
 	lineGraph.SetXAxis(data[algInd].NumNodes)
 
-	for algoNum, dataPoint := range data {
-		lineGraph.AddSeries(algoMap[algoNum], generateLineData(dataPoint.TimeElapsed),
-			charts.WithLabelOpts(opts.Label{Show: true, Position: "bottom"}))
+	for _, algoNum := range algoIds {
+		addTimeSeries(lineGraph, data[algoNum])
 	}
-
-	lineGraph.SetSeriesOptions(
-		charts.WithMarkLineNameTypeItemOpts(opts.MarkLineNameTypeItem{
-			Name: "Average",
-			Type: "average",
-		}),
-
-		charts.WithLineChartOpts(opts.LineChart{
-			Smooth: false,
-		}),
-		charts.WithMarkPointStyleOpts(opts.MarkPointStyle{
-			Label: &opts.Label{
-				Show:      true,
-				Formatter: "{a}: {b}",
-			},
-		}),
-	)
 	return lineGraph
 }
 
 func generateDegreeLineChart(data map[int]DataPoint) *charts.Line {
 	lineGraph := charts.NewLine()
 	categories := make([]*opts.GraphCategory, 0)
-	numAlgos := len(data)
+	algoIds := sortedAlgoIds(data)
 
-	for i := 0; i < numAlgos; i++ {
+	for _, i := range algoIds {
 		categories = append(categories,
 			&opts.GraphCategory{
-				Name: fmt.Sprintf("%s", algoMap[i]),
+				Name: data[i].AlgoName,
 				Label: &opts.Label{
 					Show:     true,
 					Position: "right",
@@ -162,25 +187,30 @@ func generateDegreeLineChart(data map[int]DataPoint) *charts.Line {
 	// In this case we are assuming the Number of Nodes is what changes in each new run of the test,
 	// we are assuming MaxDegree is the same for all runs of the tests.
 	var algInd int
-	for i, v := range data {
-		if v.NumNodes != nil {
+	for _, i := range algoIds {
+		if data[i].NumNodes != nil {
 			algInd = i
 			break
 		}
 	}
 	lineGraph.SetXAxis(data[algInd].MaxDegree)
 
-	for algoNum, dataPoint := range data {
-		lineGraph.AddSeries(algoMap[algoNum], generateLineData(dataPoint.TimeElapsed),
-			charts.WithLabelOpts(opts.Label{Show: true, Position: "bottom"}))
+	for _, algoNum := range algoIds {
+		addTimeSeries(lineGraph, data[algoNum])
 	}
+	return lineGraph
+}
 
-	lineGraph.SetSeriesOptions(
+// addTimeSeries adds an algorithm's mean runtimes to a line chart. If any test was repeated,
+// its 95% confidence interval is drawn as a shaded band: a dashed lower bound with the interval's width stacked on top of it.
+// The band's series share the algorithm's name, so toggling the algorithm in the legend toggles its band too
+func addTimeSeries(lineGraph *charts.Line, dataPoint DataPoint) {
+	lineGraph.AddSeries(dataPoint.AlgoName, generateLineData(dataPoint.TimeElapsed),
+		charts.WithLabelOpts(opts.Label{Show: true, Position: "bottom"}),
 		charts.WithMarkLineNameTypeItemOpts(opts.MarkLineNameTypeItem{
 			Name: "Average",
 			Type: "average",
 		}),
-
 		charts.WithLineChartOpts(opts.LineChart{
 			Smooth: false,
 		}),
@@ -191,9 +221,173 @@ func generateDegreeLineChart(data map[int]DataPoint) *charts.Line {
 			},
 		}),
 	)
+
+	repeated := false
+	for _, reps := range dataPoint.Repetitions {
+		if reps > 1 {
+			repeated = true
+		}
+	}
+	if !repeated || len(dataPoint.TimeCILow) != len(dataPoint.TimeElapsed) {
+		return
+	}
+
+	lows := make([]opts.LineData, len(dataPoint.TimeCILow))
+	widths := make([]opts.LineData, len(dataPoint.TimeCILow))
+	for i := range dataPoint.TimeCILow {
+		lows[i] = opts.LineData{Value: dataPoint.TimeCILow[i], Symbol: "none"}
+		widths[i] = opts.LineData{Value: dataPoint.TimeCIHigh[i] - dataPoint.TimeCILow[i], Symbol: "none"}
+	}
+	stack := dataPoint.AlgoName + " 95% CI"
+	lineGraph.AddSeries(dataPoint.AlgoName, lows,
+		charts.WithLineChartOpts(opts.LineChart{Stack: stack}),
+		charts.WithLineStyleOpts(opts.LineStyle{Type: "dashed", Opacity: 0.5}),
+	)
+	lineGraph.AddSeries(dataPoint.AlgoName, widths,
+		charts.WithLineChartOpts(opts.LineChart{Stack: stack}),
+		charts.WithLineStyleOpts(opts.LineStyle{Type: "dashed", Opacity: 0.5}),
+		charts.WithAreaStyleOpts(opts.AreaStyle{Opacity: 0.2}),
+	)
+}
+
+// generateRoundsLineChart is a method that generates a line chart of LOCAL-model rounds on the Y axis
+// against whichever of NumNodes or MaxDegree varies across the tests on the X axis.
+func generateRoundsLineChart(data map[int]DataPoint) *charts.Line {
+	lineGraph := charts.NewLine()
+	algoIds := sortedAlgoIds(data)
+	degIV := IsDegreeOnlyIV(data)
+
+	xName := "Number of Nodes"
+	if degIV {
+		xName = "Max Degree"
+	}
+	lineGraph.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: "Round Complexity for the different algorithms.",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Rounds",
+			SplitLine: &opts.SplitLine{
+				Show: false,
+			},
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: xName,
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Left: "60%",
+			Show: true,
+		}),
+	)
+
+	for _, i := range algoIds {
+		if data[i].NumNodes != nil {
+			if degIV {
+				lineGraph.SetXAxis(data[i].MaxDegree)
+			} else {
+				lineGraph.SetXAxis(data[i].NumNodes)
+			}
+			break
+		}
+	}
+
+	for _, algoNum := range algoIds {
+		dataPoint := data[algoNum]
+		lineGraph.AddSeries(dataPoint.AlgoName, generateLineData(dataPoint.Rounds),
+			charts.WithLabelOpts(opts.Label{Show: true, Position: "bottom"}))
+	}
+	return lineGraph
+}
+
+// generateSpeedupLineChart is a method that generates a line chart of each algorithm's speedup over a single worker on the Y axis
+// against the number of workers on the X axis, along with the ideal linear speedup.
+func generateSpeedupLineChart(data map[int]DataPoint) *charts.Line {
+	lineGraph := generateScalingLineChart(data, "Speedup over a single worker for the different algorithms.", "Speedup")
+	workers := sweepWorkers(data)
+	ideal := make([]float64, len(workers))
+	for i, w := range workers {
+		ideal[i] = float64(w)
+	}
+	lineGraph.AddSeries("Linear speedup", generateFloatLineData(ideal),
+		charts.WithLineStyleOpts(opts.LineStyle{Type: "dashed", Opacity: 0.5}))
+	for _, algoNum := range sortedAlgoIds(data) {
+		dataPoint := data[algoNum]
+		lineGraph.AddSeries(dataPoint.AlgoName, generateFloatLineData(dataPoint.Speedup),
+			charts.WithLabelOpts(opts.Label{Show: true, Position: "bottom"}))
+	}
+	return lineGraph
+}
+
+// generateEfficiencyLineChart is a method that generates a line chart of each algorithm's parallel efficiency, speedup per worker,
+// on the Y axis against the number of workers on the X axis. An efficiency of 1 is perfect scaling.
+func generateEfficiencyLineChart(data map[int]DataPoint) *charts.Line {
+	lineGraph := generateScalingLineChart(data, "Parallel efficiency for the different algorithms.", "Efficiency")
+	for _, algoNum := range sortedAlgoIds(data) {
+		dataPoint := data[algoNum]
+		lineGraph.AddSeries(dataPoint.AlgoName, generateFloatLineData(dataPoint.Efficiency),
+			charts.WithLabelOpts(opts.Label{Show: true, Position: "bottom"}))
+	}
+	return lineGraph
+}
+
+// generateScalingLineChart sets up an empty line chart with the number of workers of a pool size sweep on the X axis
+func generateScalingLineChart(data map[int]DataPoint, title string, yName string) *charts.Line {
+	lineGraph := charts.NewLine()
+	lineGraph.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: title,
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: yName,
+			SplitLine: &opts.SplitLine{
+				Show: false,
+			},
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: "Workers",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Left: "60%",
+			Show: true,
+		}),
+	)
+	lineGraph.SetXAxis(sweepWorkers(data))
 	return lineGraph
 }
 
+// sweepWorkers returns the worker counts of the first algorithm with any, which every algorithm of a sweep shares
+func sweepWorkers(data map[int]DataPoint) []int {
+	for _, i := range sortedAlgoIds(data) {
+		if data[i].Workers != nil {
+			return data[i].Workers
+		}
+	}
+	return nil
+}
+
+// IsWorkerSweep returns whether the tests were a pool size sweep, meaning the number of workers is the independent variable
+func IsWorkerSweep(data map[int]DataPoint) bool {
+	for _, alg := range data {
+		for _, speedup := range alg.Speedup {
+			if speedup > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedAlgoIds returns the algorithm IDs present in data in ascending order, so series are drawn in a stable order
+func sortedAlgoIds(data map[int]DataPoint) []int {
+	ids := make([]int, 0, len(data))
+	for id := range data {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// IsDegreeOnlyIV returns whether every test has the same number of nodes, meaning max degree is the only independent variable
 func IsDegreeOnlyIV(data map[int]DataPoint) bool {
 	numNodes := 0
 	for _, alg := range data {
@@ -214,9 +408,17 @@ func IsDegreeOnlyIV(data map[int]DataPoint) bool {
 func GenerateHTMLForDataPoints(data map[int]DataPoint, testFileName string) {
 	fmt.Printf("Generating html...\n")
 	page := components.NewPage()
-	page.AddCharts(
-		generateLineChart(data),
-	)
+	if IsWorkerSweep(data) {
+		page.AddCharts(
+			generateSpeedupLineChart(data),
+			generateEfficiencyLineChart(data),
+		)
+	} else {
+		page.AddCharts(
+			generateLineChart(data),
+			generateRoundsLineChart(data),
+		)
+	}
 	now := time.Now()
 	path := fmt.Sprintf("../html/%s-%d-%d-%d.html", testFileName[0:6], now.Hour(), now.Minute(), now.Second())
 	f, err := os.Create(path)
@@ -0,0 +1,188 @@
+package graphs
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+	Exporting colored graphs for Graphviz, Gephi and Cytoscape
+		- WriteDOT: writes a Graph as an undirected Graphviz DOT graph, filling every node with the color of its class
+		- WriteGraphML: writes a Graph as GraphML, with each node's color as an attribute
+		- WriteGEXF: writes a Graph as GEXF, with each node's color as an attribute and as its displayed color
+		- WriteGraphFile: writes a Graph to a file in the format given by its extension
+	Every format keeps the color itself as a node attribute, named color in GraphML and GEXF and colorclass in DOT,
+	where color is already the outline color. Every edge is written once, from the end that comes first in gr.Nodes.
+	Color classes are drawn in the same hues as the replays of displayReplay.go, with uncolored nodes in gray.
+*/
+
+// exportFormats maps the file extensions WriteGraphFile accepts to their writers
+var exportFormats = map[string]func(gr *Graph, w io.Writer) error{
+	".dot":     WriteDOT,
+	".gv":      WriteDOT,
+	".graphml": WriteGraphML,
+	".gexf":    WriteGEXF,
+}
+
+// IsExportFile returns whether fileName has an extension WriteGraphFile can write
+func IsExportFile(fileName string) bool {
+	_, ok := exportFormats[strings.ToLower(filepath.Ext(fileName))]
+	return ok
+}
+
+// WriteGraphFile writes a Graph to fileName as DOT (.dot or .gv), GraphML (.graphml) or GEXF (.gexf)
+func WriteGraphFile(gr *Graph, fileName string) error {
+	write, ok := exportFormats[strings.ToLower(filepath.Ext(fileName))]
+	if !ok {
+		return fmt.Errorf("unsupported export format %s", filepath.Ext(fileName))
+	}
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := write(gr, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteDOT writes a Graph as an undirected Graphviz graph, ready for dot, neato or sfdp.
+// The Graph's Description is written as a comment, and every node is labelled with its name and filled with its color class
+func WriteDOT(gr *Graph, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	position := nodePositions(gr)
+
+	fmt.Fprintf(bw, "// %s\n", strings.ReplaceAll(gr.Description, "\n", " "))
+	fmt.Fprintf(bw, "graph %s {\n", dotID(gr.Name))
+	fmt.Fprintf(bw, "\tnode [style=filled];\n")
+	for _, node := range gr.Nodes {
+		fmt.Fprintf(bw, "\t%s [colorclass=%d, fillcolor=\"%s\"];\n", dotID(node.Name), node.Color, colorHex(node.Color))
+	}
+	for i, node := range gr.Nodes {
+		for _, neighbor := range node.Neighbors {
+			if position(neighbor) > i {
+				fmt.Fprintf(bw, "\t%s -- %s;\n", dotID(node.Name), dotID(neighbor.Name))
+			}
+		}
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// dotID quotes a name as a DOT identifier, escaping any quotes and backslashes within it
+func dotID(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// WriteGraphML writes a Graph as GraphML, whose nodes carry their color as the integer attribute color
+// and its display value as the string attribute fill
+func WriteGraphML(gr *Graph, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	position := nodePositions(gr)
+
+	fmt.Fprintf(bw, "%s", xml.Header)
+	fmt.Fprintf(bw, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\"")
+	fmt.Fprintf(bw, " xsi:schemaLocation=\"http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd\">\n")
+	fmt.Fprintf(bw, "\t<key id=\"color\" for=\"node\" attr.name=\"color\" attr.type=\"int\"/>\n")
+	fmt.Fprintf(bw, "\t<key id=\"fill\" for=\"node\" attr.name=\"fill\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "\t<key id=\"description\" for=\"graph\" attr.name=\"description\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "\t<graph id=\"%s\" edgedefault=\"undirected\">\n", xmlText(gr.Name))
+	fmt.Fprintf(bw, "\t\t<data key=\"description\">%s</data>\n", xmlText(gr.Description))
+	for _, node := range gr.Nodes {
+		fmt.Fprintf(bw, "\t\t<node id=\"%s\">\n", xmlText(node.Name))
+		fmt.Fprintf(bw, "\t\t\t<data key=\"color\">%d</data>\n", node.Color)
+		fmt.Fprintf(bw, "\t\t\t<data key=\"fill\">%s</data>\n", colorHex(node.Color))
+		fmt.Fprintf(bw, "\t\t</node>\n")
+	}
+	for i, node := range gr.Nodes {
+		for _, neighbor := range node.Neighbors {
+			if position(neighbor) > i {
+				fmt.Fprintf(bw, "\t\t<edge source=\"%s\" target=\"%s\"/>\n", xmlText(node.Name), xmlText(neighbor.Name))
+			}
+		}
+	}
+	fmt.Fprintf(bw, "\t</graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// WriteGEXF writes a Graph as GEXF 1.3, whose nodes carry their color as the integer attribute color
+// and are displayed in the color of their class
+func WriteGEXF(gr *Graph, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	position := nodePositions(gr)
+
+	fmt.Fprintf(bw, "%s", xml.Header)
+	fmt.Fprintf(bw, "<gexf xmlns=\"http://gexf.net/1.3\" xmlns:viz=\"http://gexf.net/1.3/viz\" version=\"1.3\">\n")
+	fmt.Fprintf(bw, "\t<meta>\n\t\t<description>%s</description>\n\t</meta>\n", xmlText(gr.Description))
+	fmt.Fprintf(bw, "\t<graph defaultedgetype=\"undirected\">\n")
+	fmt.Fprintf(bw, "\t\t<attributes class=\"node\">\n\t\t\t<attribute id=\"color\" title=\"color\" type=\"integer\"/>\n\t\t</attributes>\n")
+	fmt.Fprintf(bw, "\t\t<nodes>\n")
+	for _, node := range gr.Nodes {
+		r, g, b := colorRGB(node.Color)
+		fmt.Fprintf(bw, "\t\t\t<node id=\"%s\" label=\"%s\">\n", xmlText(node.Name), xmlText(node.Name))
+		fmt.Fprintf(bw, "\t\t\t\t<attvalues><attvalue for=\"color\" value=\"%d\"/></attvalues>\n", node.Color)
+		fmt.Fprintf(bw, "\t\t\t\t<viz:color r=\"%d\" g=\"%d\" b=\"%d\"/>\n", r, g, b)
+		fmt.Fprintf(bw, "\t\t\t</node>\n")
+	}
+	fmt.Fprintf(bw, "\t\t</nodes>\n\t\t<edges>\n")
+	numEdges := 0
+	for i, node := range gr.Nodes {
+		for _, neighbor := range node.Neighbors {
+			if position(neighbor) > i {
+				fmt.Fprintf(bw, "\t\t\t<edge id=\"%d\" source=\"%s\" target=\"%s\"/>\n", numEdges, xmlText(node.Name), xmlText(neighbor.Name))
+				numEdges++
+			}
+		}
+	}
+	fmt.Fprintf(bw, "\t\t</edges>\n\t</graph>\n</gexf>\n")
+	return bw.Flush()
+}
+
+// xmlText escapes a string for use as XML text or as an attribute value
+func xmlText(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// colorHex returns the color of a color class as #rrggbb
+func colorHex(color int) string {
+	r, g, b := colorRGB(color)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// colorRGB returns the color of a color class as red, green and blue, matching replayColor:
+// hsl(color*137 mod 360, 70%, 50%) for colors >= 0, taking color mod 360 first so large colors cannot overflow, and gray for uncolored nodes
+func colorRGB(color int) (uint8, uint8, uint8) {
+	if color < 0 {
+		return 0xcc, 0xcc, 0xcc
+	}
+	hue := float64((color % 360 * 137) % 360)
+	const saturation, lightness = 0.7, 0.5
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = chroma, x, 0
+	case hue < 120:
+		r, g, b = x, chroma, 0
+	case hue < 180:
+		r, g, b = 0, chroma, x
+	case hue < 240:
+		r, g, b = 0, x, chroma
+	case hue < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	m := lightness - chroma/2
+	return uint8(math.Round((r + m) * 255)), uint8(math.Round((g + m) * 255)), uint8(math.Round((b + m) * 255))
+}
@@ -1,8 +1,9 @@
 package graphs
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"sort"
 )
 
 /*
@@ -12,11 +13,39 @@ import (
 /*
 	Useful functions offered by this file:
 		- IsSafe: checks for color differences between neighbors, based on https://www.geeksforgeeks.org/m-coloring-problem-backtracking-5/
+			See verify.go for Verify, which reports every conflict and bound violation instead
 		- GetNamesFromNodeList: converts a list of Node pointers to a list of string Node names
 		- PrintGraph: prints a graph
 		- NodeMatch: convert a map of names into map of pointers
+		See csr.go for CSR, a compact index-based form of a Graph that algorithms can run on directly
+		See export.go for writing a colored Graph as DOT, GraphML or GEXF
 */
 
+var (
+	// ErrDirectedEdge is returned when a node lists a neighbor that does not list it back
+	ErrDirectedEdge = errors.New("directed edge")
+	// ErrUnknownNeighbor is returned when a node lists a neighbor that was never defined
+	ErrUnknownNeighbor = errors.New("unknown neighbor")
+)
+
+// GraphError describes a malformed edge found while matching Nodes to their neighbors
+//		Kind: one of the Err* sentinel errors, so callers can use errors.Is
+//		Node: the name of the Node whose neighbor list is malformed
+//		Neighbor: the name of the offending neighbor
+type GraphError struct {
+	Kind error
+	Node string
+	Neighbor string
+}
+
+func (e *GraphError) Error() string {
+	return fmt.Sprintf("%s: node %s lists neighbor %s", e.Kind, e.Node, e.Neighbor)
+}
+
+func (e *GraphError) Unwrap() error {
+	return e.Kind
+}
+
 // Graph is a struct storing metadata and a list of nodes
 //		Name: the name of the graph
 //		Description: a description for the graph
@@ -54,31 +83,55 @@ func IsSafe(gr *Graph) bool {
 	return true
 }
 
-// DeepCopy copies the Graph and all of its Nodes to hand over to another algorithm
+// DeepCopy copies the Graph and all of its Nodes to hand over to another algorithm.
+// Neighbors are matched by position in gr.Nodes rather than by name, so the copy never hashes a string
 func DeepCopy(gr *Graph) Graph {
-	var nodeList []*Node
-
-	var nodeNameMap map[string]*Node
-	nodeNameMap = make(map[string]*Node)
-
-	var nodeNeighborNameMap map[string][]string
-	nodeNeighborNameMap = make(map[string][]string)
-
-	for _, node := range gr.Nodes {
-		newNode := Node{Name:node.Name, Ind: node.Ind, Color:node.Color}
-		nodeList = append(nodeList, &newNode)
-		nodeNameMap[node.Name] = &newNode
-		nodeNeighborNameMap[node.Name] = GetNamesFromNodeList(node.Neighbors)
+	position := nodePositions(gr)
+	nodes := make([]Node, len(gr.Nodes))
+	nodeList := make([]*Node, len(gr.Nodes))
+	numEdges := 0
+	for i, node := range gr.Nodes {
+		nodes[i] = Node{Name: node.Name, Ind: node.Ind, Color: node.Color}
+		nodeList[i] = &nodes[i]
+		numEdges += len(node.Neighbors)
 	}
 
-	copiedNodes := NodeMatch(nodeList, nodeNameMap, nodeNeighborNameMap)
+	// Every neighbor list is a window of one shared array, capped so that appending to one cannot overwrite the next
+	adj := make([]*Node, 0, numEdges)
+	for i, node := range gr.Nodes {
+		start := len(adj)
+		for _, neighbor := range node.Neighbors {
+			adj = append(adj, nodeList[position(neighbor)])
+		}
+		nodeList[i].Neighbors = adj[start:len(adj):len(adj)]
+	}
 
 	return Graph{
 		Name: gr.Name,
 		Description: gr.Description,
 		MaxDegree: gr.MaxDegree,
-		Nodes: copiedNodes,
+		Nodes: nodeList,
+	}
+}
+
+// nodePositions returns a function giving the position of a Node in gr.Nodes.
+// That is its Ind whenever every Ind matches its position, as parsing guarantees, and a pointer lookup otherwise
+func nodePositions(gr *Graph) func(n *Node) int {
+	indsMatch := true
+	for i, node := range gr.Nodes {
+		if node.Ind != i {
+			indsMatch = false
+			break
+		}
+	}
+	if indsMatch {
+		return func(n *Node) int { return n.Ind }
+	}
+	positions := make(map[*Node]int, len(gr.Nodes))
+	for i, node := range gr.Nodes {
+		positions[node] = i
 	}
+	return func(n *Node) int { return positions[n] }
 }
 
 // GetNamesFromNodeList converts a list of Node pointers to their names
@@ -117,37 +170,65 @@ func contains(strs []string, query string) bool {
 //		nList: an array of Node pointers that is edited directly to attach its neighbors
 //		nNameMap: a map of string Node names to their pointers
 //		nNeighborNameMap: a map of a Node's string name to its string-named neighbors
-// This is used during copying and parsing
-func NodeMatch(nList []*Node, nNameMap map[string]*Node, nNeighborNameMap map[string][]string) []*Node {
-	var nNeighborMap map[string][]*Node
-	nNeighborMap = make(map[string][]*Node)
-
-	//Construct proper nNeighborMap
-	for k, v := range nNeighborNameMap {
-		neighborPointers := make([]*Node, 0)
-		for _, neighborName := range v {
-			//Check for directed edges
-			n2Neighbors, ok := nNeighborNameMap[neighborName]
-			if !ok || !contains(n2Neighbors, k) {
-				log.Fatalf( "DIRECTED EDGE, neighbor node %s lacks reverse pointer to %s", neighborName, k)
-			}
+// This is used during parsing
+// Returns a *GraphError for the first node, in nList order, that has an unknown neighbor or a directed edge.
+// Each name is hashed once, and reverse edges are then found by binary search over sorted positions in nList,
+// so matching takes O(E log MaxDegree) instead of comparing neighbor names pairwise
+func NodeMatch(nList []*Node, nNameMap map[string]*Node, nNeighborNameMap map[string][]string) ([]*Node, error) {
+	position := make(map[*Node]int32, len(nList))
+	for i, node := range nList {
+		position[node] = int32(i)
+	}
 
-			//Retrieve neighbor pointer
-			neighbor, ok := nNameMap[neighborName]
-			if !ok {
-				log.Fatalf( "Parsing error, neighbor node pointer not found for %s with neighbor %s", k, neighborName)
+	//Resolve every neighbor name to a pointer, and to a position in nList if it has one
+	neighborPointers := make([][]*Node, len(nList))
+	sortedPositions := make([][]int32, len(nList))
+	for i, node := range nList {
+		names := nNeighborNameMap[node.Name]
+		pointers := make([]*Node, len(names))
+		positions := make([]int32, 0, len(names))
+		for j, neighborName := range names {
+			neighbor := nNameMap[neighborName]
+			pointers[j] = neighbor
+			if pos, ok := position[neighbor]; ok && neighbor != nil {
+				positions = append(positions, pos)
 			}
-			neighborPointers = append(neighborPointers, neighbor)
 		}
+		sort.Slice(positions, func(a, b int) bool { return positions[a] < positions[b] })
+		neighborPointers[i] = pointers
+		sortedPositions[i] = positions
+	}
+
+	for i, node := range nList {
+		for j, neighbor := range neighborPointers[i] {
+			if neighbor == nil {
+				return nil, &GraphError{Kind: ErrUnknownNeighbor, Node: node.Name, Neighbor: nNeighborNameMap[node.Name][j]}
+			}
 
-		nNeighborMap[k] = neighborPointers
+			//Check for directed edges
+			listsBack := false
+			if pos, ok := position[neighbor]; ok {
+				listsBack = containsPosition(sortedPositions[pos], int32(i))
+			} else {
+				listsBack = contains(nNeighborNameMap[neighbor.Name], node.Name)
+			}
+			if !listsBack {
+				return nil, &GraphError{Kind: ErrDirectedEdge, Node: node.Name, Neighbor: neighbor.Name}
+			}
+		}
 	}
 
-	//Retains original node order, rebuilds nodeList
-	for _, node := range nList {
-		node.Neighbors = nNeighborMap[node.Name]
+	//Retains original node order
+	for i, node := range nList {
+		node.Neighbors = neighborPointers[i]
 	}
-	return nList
+	return nList, nil
+}
+
+// containsPosition is a helper method for whether or not a position is within a sorted array
+func containsPosition(sorted []int32, query int32) bool {
+	i := sort.Search(len(sorted), func(k int) bool { return sorted[k] >= query })
+	return i < len(sorted) && sorted[i] == query
 }
 
 // RunColorInit sets all of the Node's colors in a Graph to their index in the Graph's Nodes
@@ -158,23 +239,11 @@ func RunColorInit(gr *Graph) *Graph {
 	return gr
 }
 
-// intContains is a helper method for whether or not an int query is within an array
-func intContains(nums []int, query int) bool {
-	for _, v := range nums {
-		if v == query {
-			return true
-		}
-	}
-	return false
-}
-
 // CountColors counts the total number of unique colors within a Graph
 func CountColors(gr *Graph) int {
-	var allColors []int
+	set := newColorSet(colorSetLimit(gr))
 	for _, node := range gr.Nodes {
-		if !intContains(allColors, node.Color) {
-			allColors = append(allColors, node.Color)
-		}
+		set.add(node.Color)
 	}
-	return len(allColors)
-}
\ No newline at end of file
+	return set.count()
+}
@@ -0,0 +1,217 @@
+package graphs
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+/*
+	Useful functions offered by this file:
+		- Verify: checks a coloring in full, reporting every conflict and bound violation instead of stopping at the first
+		- VerifyParallel: Verify with the Nodes sharded across workers, for graphs with millions of edges
+		- VerifyReport.Status: sums up a VerifyReport as safe, too-many-colors or unsafe
+*/
+
+const (
+	// StatusSafe is a proper coloring with at most MaxDegree+1 colors
+	StatusSafe = "safe"
+	// StatusTooManyColors is a proper coloring with more than MaxDegree+1 colors
+	StatusTooManyColors = "too-many-colors"
+	// StatusUnsafe is a coloring with a conflicting edge or a node without a valid color
+	StatusUnsafe = "unsafe"
+)
+
+// Conflict is an edge whose two ends have the same color
+//		A, B: the names of the two Nodes, with A before B in the Graph's Nodes
+//		Color: the color they share
+type Conflict struct {
+	A string
+	B string
+	Color int
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s -- %s (color %d)", c.A, c.B, c.Color)
+}
+
+// VerifyReport is the result of checking a coloring against its Graph
+//		Conflicts: every edge whose ends share a color, listed once each
+//		InvalidColors: the names of every Node with a negative color, which algorithms use for unset
+//		NumColors: the number of distinct colors used
+//		ColorBound: MaxDegree+1, the most colors a reduction is meant to leave
+//		WithinBound: whether NumColors <= ColorBound
+//		DeclaredMaxDegree: the Graph's MaxDegree, as given by its file
+//		ActualMaxDegree: the largest number of neighbors of any Node
+//		DegreeMatches: whether DeclaredMaxDegree == ActualMaxDegree. Parsing only guarantees the declared degree is not too small
+type VerifyReport struct {
+	Conflicts []Conflict
+	InvalidColors []string
+	NumColors int
+	ColorBound int
+	WithinBound bool
+	DeclaredMaxDegree int
+	ActualMaxDegree int
+	DegreeMatches bool
+}
+
+// Safe returns whether the coloring is proper: no conflicts and no invalid colors
+func (r VerifyReport) Safe() bool {
+	return len(r.Conflicts) == 0 && len(r.InvalidColors) == 0
+}
+
+// Status returns StatusUnsafe if the coloring is not proper, StatusTooManyColors if it is proper but over ColorBound,
+// and StatusSafe otherwise
+func (r VerifyReport) Status() string {
+	if !r.Safe() {
+		return StatusUnsafe
+	}
+	if !r.WithinBound {
+		return StatusTooManyColors
+	}
+	return StatusSafe
+}
+
+// Verify checks every Node and edge of a colored Graph and reports all that is wrong with its coloring
+func Verify(gr *Graph) VerifyReport {
+	report := VerifyReport{}
+	set := newColorSet(colorSetLimit(gr))
+	verifyNodes(gr.Nodes, &report, set)
+	finishReport(gr, &report, set)
+	return report
+}
+
+// VerifyParallel is Verify with gr.Nodes split into contiguous shards across numWorkers goroutines.
+// Each shard is checked into its own partial report and color set, which are merged in shard order,
+// so the result is identical to Verify's, down to the order of Conflicts and InvalidColors
+func VerifyParallel(gr *Graph, numWorkers int) VerifyReport {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if numWorkers > len(gr.Nodes) {
+		numWorkers = len(gr.Nodes)
+	}
+	if numWorkers <= 1 {
+		return Verify(gr)
+	}
+	limit := colorSetLimit(gr)
+	chunk := (len(gr.Nodes) + numWorkers - 1) / numWorkers
+	numShards := (len(gr.Nodes) + chunk - 1) / chunk
+	partials := make([]VerifyReport, numShards)
+	sets := make([]*colorSet, numShards)
+
+	var wg sync.WaitGroup
+	for shard := 0; shard < numShards; shard++ {
+		start := shard * chunk
+		end := start + chunk
+		if end > len(gr.Nodes) {
+			end = len(gr.Nodes)
+		}
+		wg.Add(1)
+		go func(shard int, nodes []*Node) {
+			defer wg.Done()
+			sets[shard] = newColorSet(limit)
+			verifyNodes(nodes, &partials[shard], sets[shard])
+		}(shard, gr.Nodes[start:end])
+	}
+	wg.Wait()
+
+	report := VerifyReport{}
+	set := sets[0]
+	for shard, partial := range partials {
+		report.Conflicts = append(report.Conflicts, partial.Conflicts...)
+		report.InvalidColors = append(report.InvalidColors, partial.InvalidColors...)
+		if partial.ActualMaxDegree > report.ActualMaxDegree {
+			report.ActualMaxDegree = partial.ActualMaxDegree
+		}
+		if shard > 0 {
+			set.merge(sets[shard])
+		}
+	}
+	finishReport(gr, &report, set)
+	return report
+}
+
+// verifyNodes checks the given Nodes and the edges they come first on into report, adding their colors to set
+func verifyNodes(nodes []*Node, report *VerifyReport, set *colorSet) {
+	for _, node := range nodes {
+		set.add(node.Color)
+		if len(node.Neighbors) > report.ActualMaxDegree {
+			report.ActualMaxDegree = len(node.Neighbors)
+		}
+		if node.Color < 0 {
+			report.InvalidColors = append(report.InvalidColors, node.Name)
+			continue
+		}
+		for _, neighbor := range node.Neighbors {
+			// Each edge is seen from both ends, so only report it from the end that comes first
+			if neighbor.Ind < node.Ind || node.Color != neighbor.Color {
+				continue
+			}
+			report.Conflicts = append(report.Conflicts, Conflict{A: node.Name, B: neighbor.Name, Color: node.Color})
+		}
+	}
+}
+
+// finishReport fills in the parts of a report that need the whole Graph, once every Node has been checked
+func finishReport(gr *Graph, report *VerifyReport, set *colorSet) {
+	report.DeclaredMaxDegree = gr.MaxDegree
+	report.ColorBound = gr.MaxDegree + 1
+	report.NumColors = set.count()
+	report.WithinBound = report.NumColors <= report.ColorBound
+	report.DegreeMatches = report.DeclaredMaxDegree == report.ActualMaxDegree
+}
+
+// colorSet records which colors appear in a Graph: a bitmap for the colors in [0, limit), which covers
+// both reduced colorings and colors initialized to each Node's index, and a map for any others, such as random IDs
+type colorSet struct {
+	bits  []uint64
+	limit int
+	other map[int]struct{}
+}
+
+// colorSetLimit returns the bitmap size to use for a Graph's colors
+func colorSetLimit(gr *Graph) int {
+	limit := len(gr.Nodes)
+	if gr.MaxDegree+1 > limit {
+		limit = gr.MaxDegree + 1
+	}
+	return limit
+}
+
+// newColorSet builds an empty colorSet with a bitmap for the colors in [0, limit)
+func newColorSet(limit int) *colorSet {
+	return &colorSet{
+		bits:  make([]uint64, (limit+63)/64),
+		limit: limit,
+		other: make(map[int]struct{}),
+	}
+}
+
+// add records that a color appears
+func (s *colorSet) add(color int) {
+	if color >= 0 && color < s.limit {
+		s.bits[color/64] |= 1 << uint(color%64)
+		return
+	}
+	s.other[color] = struct{}{}
+}
+
+// merge adds every color recorded in o, which must have the same limit
+func (s *colorSet) merge(o *colorSet) {
+	for i, word := range o.bits {
+		s.bits[i] |= word
+	}
+	for color := range o.other {
+		s.other[color] = struct{}{}
+	}
+}
+
+// count returns the number of distinct colors recorded
+func (s *colorSet) count() int {
+	total := len(s.other)
+	for _, word := range s.bits {
+		total += bits.OnesCount64(word)
+	}
+	return total
+}
@@ -3,22 +3,43 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	gen "github.com/thomaseb191/go-coloring/generator"
 	g "github.com/thomaseb191/go-coloring/graphs"
 	r "github.com/thomaseb191/go-coloring/reductions"
 	t "github.com/thomaseb191/go-coloring/testHarness"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 // If running from goland, paths should be res/...
 // If running from terminal within src/, paths should be ../res/...
 // Examples of calls after running 'go build main.go' include
+//		- ./main.exe list
 //		- ./main.exe ../testFiles/test01_naive.txt
 //		- ./main.exe ../res/Sample01.txt []
 //		- ./main.exe ../res/Sample01.txt [] -1
 //		- ./main.exe ../res/Sample01.txt [] -1 3
+//		- ./main.exe ../res/Sample01.txt [naive,kw] -1 3
+//		- ./main.exe ../res/Sample01.txt [] -1 3 congest=2
+//		- ./main.exe ../res/Sample01.txt [] -1 0 warmup=2 reps=10
+//		- ./main.exe ../res/Sample02.txt [cv,kw] -1 0 replay=true
+//		- ./main.exe ../res/Graph_N5000_D5.txt [kw,dlf] -1 0 reps=5 sweep=1,2,4,8
+//		- ./main.exe ../res/myciel3.col [] -1 0
+//		- ./main.exe ../res/Sample04.edges [] -1 0
+//		- ./main.exe metis:../res/Sample05.graph [naive,dlf] -1 0
+//		- ./main.exe ../res/Graph_N50000_D10.txt [naive,dlf] -1 0 csr=true
+//		- ./main.exe ../res/Graph_N1000_D5.txt [cv,dlf,johansson] -1 0 seed=42
+//		- ./main.exe ../res/Graph_N1000_D5.txt [naive,cv,linial] -1 0 init=random seed=42
+//		- ./main.exe ../res/Sample01.txt [kw] -1 0 init=sidecar
+//		- ./main.exe ../res/Graph_ring_N1000.txt [cv,cv3] -1 0 topology=cycle
+//		- ./main.exe convert ../res/Sample01.txt ../res/Sample01.col
+//		- ./main.exe convert ../res/myciel3.col ../html/myciel3.gexf
+//		- ./main.exe ../res/Sample02.txt [dlf] -1 0 export=dot
+//		- ./main.exe generate gnp ../res/Graph_gnp.txt n=1000000 p=0.000005 maxdeg=20 seed=1
+//		- ./main.exe generate regular ../res/Graph_regular.col n=100000 d=8 seed=7
 func main() {
 	inputArgs := os.Args
 	if len(inputArgs) == 1 {
@@ -26,21 +47,45 @@ func main() {
 		// TODO: CHANGE TO DESIRED DEFAULT BEHAVIOR
 		fmt.Printf("\n\n\n")
 
-		tResults := t.RunTest("../res/Sample02.txt", []int{}, -1, 3)
+		tResults, err := t.RunTest(t.TestDirective{GraphFile: "../res/Sample02.txt", Algos: []int{}, PoolSize: -1, Debug: 3})
+		if err != nil {
+			log.Fatal(err)
+		}
 		for i := 0; i < len(tResults); i++ {
 			fmt.Printf("Duration of test %s: %d with %d colors\n", tResults[i].Name, tResults[i].DurationMillis.Nanoseconds(), tResults[i].NumColors)
 			g.PrintGraph(&tResults[i].Output)
 		}
 
+	} else if len(inputArgs) == 2 && inputArgs[1] == "list" {
+		// List the registered algorithms
+		listAlgorithms()
+	} else if len(inputArgs) == 4 && inputArgs[1] == "convert" {
+		// Convert a graph file to another format
+		if err := convertGraph(inputArgs[2], inputArgs[3]); err != nil {
+			log.Fatal(err)
+		}
+	} else if len(inputArgs) >= 4 && inputArgs[1] == "generate" {
+		// Generate a graph from a model, e.g. generate gnp out.txt n=1000 p=0.01 maxdeg=10 seed=42
+		if err := generateGraph(inputArgs[2], inputArgs[3], inputArgs[4:]); err != nil {
+			log.Fatal(err)
+		}
+	} else if len(inputArgs) == 2 && inputArgs[1] == "generate" {
+		fmt.Printf("Usage: generate <model> <outFile> [key=value ...]\nModels: %s\n", strings.Join(gen.Models(), ", "))
 	} else if len(inputArgs) == 2 {
 		// Read in file with list of tests to run
 		testFileName := os.Args[1]
-		testDirectives := t.ParseTestFile(testFileName)
+		testDirectives, lineErrs, err := t.ParseTestFile(testFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		runTestAndPrintResultAndTrends(testDirectives, testFileName)
-	} else if len(inputArgs) >= 3 && len(inputArgs) <= 5 {
+		runTestAndPrintResultAndTrends(testDirectives, lineErrs, testFileName)
+	} else if len(inputArgs) >= 3 {
 		// Run a singular test
-		td := t.ParseArgsList(os.Args[1:])
+		td, err := t.ParseArgsList(os.Args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		runTestAndPrintResult(td, td.Debug)
 	} else {
@@ -50,69 +95,225 @@ func main() {
 
 // runTestAndPrintResults is a helper method to run a specific test set
 func runTestAndPrintResult(td t.TestDirective, debug int) {
-	tResults := t.RunTest(td.GraphFile, td.Algos, td.PoolSize, td.Debug)
+	tResults, err := t.RunTest(td)
+	if err != nil {
+		fmt.Printf("Test of %s failed: %s\n", td.GraphFile, err)
+		fmt.Printf("\n-------------------------\n")
+		return
+	}
+	if td.ExpectErr != "" {
+		fmt.Printf("Got expected error %s for %s\n", td.ExpectErr, td.GraphFile)
+	}
 	for _, k := range tResults {
 		fmt.Printf("Test Name: %s\n", k.Name)
 		if debug % 2 == 1 {
 			g.PrintGraph(&k.Output)
 		}
-		fmt.Printf("IsSafe: %t\tNum Colors: %d\tDurationNanos: %d\n", k.IsSafe, k.NumColors, k.DurationMillis.Nanoseconds())
+		fmt.Printf("IsSafe: %t\tStatus: %s\tNum Colors: %d\tDurationNanos: %d\tSeed: %d\n", k.IsSafe, k.Report.Status(), k.NumColors, k.DurationMillis.Nanoseconds(), k.Seed)
+		fmt.Printf("Rounds: %d\tMessages: %d\tMax Node Messages: %d\tBits: %d\tMax Edge Bits: %d\tWorkers: %d\n", k.Stats.Rounds, k.Stats.Messages, k.Stats.MaxNodeMessages, k.Stats.Bits, k.Stats.MaxEdgeBits, k.Stats.Workers)
+		if td.Init != "" {
+			fmt.Printf("Initial Colors: %d\tLargest Initial Color: %d\n", k.InitColors, k.InitMaxColor)
+		}
+		printKWPhases(k, "")
+		printCVIterations(k, "")
+		if k.BadReps > 0 {
+			fmt.Printf("Unsafe or Over-Bound Reps: %d of %d, reporting the worst\n", k.BadReps, k.Timing.Reps())
+		}
+		if k.Timing.Reps() > 1 {
+			printTiming(k.Timing)
+		}
+		if k.Speedup > 0 {
+			fmt.Printf("Workers: %d\tSpeedup: %.2f\tEfficiency: %.2f\n", k.Stats.Workers, k.Speedup, k.Efficiency)
+		}
+		if k.Stats.BitCap > 0 {
+			fmt.Printf("CONGEST Cap: %d bits\tCompliant: %t\tOversized Messages: %d\tCONGEST Rounds: %d\n", k.Stats.BitCap, k.Stats.CongestCompliant(), k.Stats.OversizedMessages, k.Stats.CongestRounds)
+		}
 	}
 	fmt.Printf("\n-------------------------\n")
 }
 
 // runTestAndPrintResultAndTrends is a helper method to print results of tests and generate the trend lines and output results to json
-func runTestAndPrintResultAndTrends(tds []t.TestDirective, testFileName string) {
-	var tTestNames [r.NumAlgos][]string
-	var tNumNodes [r.NumAlgos][]int
-	var tTimeElapsed [r.NumAlgos][]int
-	var tNumberColors [r.NumAlgos][]int
-	var tMaxDegree [r.NumAlgos][]int
-	var tIsSafe [r.NumAlgos][]bool
-
-	for _, td := range tds {
-		//Run Tests
-		testResults := t.RunTest(td.GraphFile, td.Algos, td.PoolSize, td.Debug)
-
-		algos := td.Algos
-		if len(algos) == 0 {
-			algos = r.AllAlgIds
-		}
-		//Extract and format data into arrays
-		for i, test := range testResults {
-			currAlg := algos[i]
-			tTestNames[currAlg] = append(tTestNames[currAlg], test.Name)
-			tNumNodes[currAlg] = append(tNumNodes[currAlg], len(test.Output.Nodes))
-			tTimeElapsed[currAlg]= append(tTimeElapsed[currAlg], int(test.DurationMillis.Nanoseconds())) //NOTE: CHANGED TO NANOSECONDS
-			tNumberColors[currAlg] = append(tNumberColors[currAlg], test.NumColors)
-			tMaxDegree[currAlg] = append(tMaxDegree[currAlg], test.Output.MaxDegree)
-			tIsSafe[currAlg] = append(tIsSafe[currAlg], test.IsSafe)
+// lineErrs are the lines of the test file that did not parse, which are reported with the directives that failed to run
+func runTestAndPrintResultAndTrends(tds []t.TestDirective, lineErrs []*t.ParseError, testFileName string) {
+	tResults := make(map[int]g.DataPoint)
+	algoNames := r.AlgoNames()
 
-			fmt.Printf("Test Name: %s\n", test.Name)
-			fmt.Printf("\tDurationNanos: %d\tNumColors: %d\tIsSafe: %t\n", test.DurationMillis.Nanoseconds(), test.NumColors, test.IsSafe)
-		}
+	var failures []string
+	for _, lineErr := range lineErrs {
+		fmt.Printf("Line %d of %s skipped: %s\n", lineErr.Line, testFileName, lineErr.Err)
+		failures = append(failures, fmt.Sprintf("line %d: %s", lineErr.Line, lineErr.Err))
 	}
-	//Format data into DataPoints
-	var tResults map[int]g.DataPoint
-	tResults = make(map[int]g.DataPoint)
 
-	for _, id := range r.AllAlgIds {
-		tResults[id] = g.DataPoint{
-			Names:		  tTestNames[id],
-			NumNodes:     tNumNodes[id],
-			TimeElapsed:  tTimeElapsed[id],
-			NumberColors: tNumberColors[id],
-			MaxDegree:	  tMaxDegree[id],
-			IsSafe:       tIsSafe[id],
+	for i, td := range tds {
+		//Run Tests, recording failed directives without stopping the rest
+		testResults, err := t.RunTest(td)
+		if err != nil {
+			fmt.Printf("Directive %d (%s) failed: %s\n", i+1, td.GraphFile, err)
+			failures = append(failures, fmt.Sprintf("%d (%s): %s", i+1, td.GraphFile, err))
+			continue
+		}
+		if td.ExpectErr != "" {
+			fmt.Printf("Directive %d (%s) got expected error %s\n", i+1, td.GraphFile, td.ExpectErr)
+			continue
+		}
+
+		//Extract and format data into DataPoints
+		for _, test := range testResults {
+			currAlg := test.AlgoID
+			if id, ok := r.LookupShortID(test.Stats.Fallback); ok {
+				// A run that fell back to another algorithm is that algorithm's data point, so trends never mix the two
+				currAlg = id
+			}
+			dp := tResults[currAlg]
+			dp.AlgoName = algoNames[currAlg]
+			dp.Names = append(dp.Names, test.Name)
+			dp.NumNodes = append(dp.NumNodes, len(test.Output.Nodes))
+			dp.TimeElapsed = append(dp.TimeElapsed, int(test.DurationMillis.Nanoseconds())) //NOTE: CHANGED TO NANOSECONDS
+			dp.Repetitions = append(dp.Repetitions, test.Timing.Reps())
+			dp.TimeMin = append(dp.TimeMin, int(test.Timing.Min))
+			dp.TimeMedian = append(dp.TimeMedian, int(test.Timing.Median))
+			dp.TimeStdDev = append(dp.TimeStdDev, int(test.Timing.StdDev))
+			dp.TimeCILow = append(dp.TimeCILow, int(test.Timing.CILow))
+			dp.TimeCIHigh = append(dp.TimeCIHigh, int(test.Timing.CIHigh))
+			dp.NumberColors = append(dp.NumberColors, test.NumColors)
+			dp.MaxDegree = append(dp.MaxDegree, test.Output.MaxDegree)
+			dp.IsSafe = append(dp.IsSafe, test.IsSafe)
+			dp.Status = append(dp.Status, test.Report.Status())
+			dp.Conflicts = append(dp.Conflicts, len(test.Report.Conflicts))
+			dp.Rounds = append(dp.Rounds, test.Stats.Rounds)
+			dp.Messages = append(dp.Messages, test.Stats.Messages)
+			dp.MaxNodeMessages = append(dp.MaxNodeMessages, test.Stats.MaxNodeMessages)
+			dp.Bits = append(dp.Bits, test.Stats.Bits)
+			dp.CongestRounds = append(dp.CongestRounds, test.Stats.CongestRounds)
+			dp.CongestCompliant = append(dp.CongestCompliant, test.Stats.CongestCompliant())
+			dp.Workers = append(dp.Workers, test.Stats.Workers)
+			dp.Speedup = append(dp.Speedup, test.Speedup)
+			dp.Efficiency = append(dp.Efficiency, test.Efficiency)
+			dp.Seeds = append(dp.Seeds, test.Seed)
+			dp.InitColors = append(dp.InitColors, test.InitColors)
+			dp.InitMaxColor = append(dp.InitMaxColor, test.InitMaxColor)
+			dp.KWPhases = append(dp.KWPhases, len(test.Stats.KWPhases))
+			dp.KWRoundBound = append(dp.KWRoundBound, kwRoundBound(test))
+			dp.CVIterations = append(dp.CVIterations, test.Stats.CVIterations)
+			dp.LogStar = append(dp.LogStar, r.LogStar(float64(test.InitMaxColor)+1))
+			tResults[currAlg] = dp
+
+			fmt.Printf("Test Name: %s\n", test.Name)
+			fmt.Printf("\tDurationNanos: %d\tNumColors: %d\tIsSafe: %t\tStatus: %s\n", test.DurationMillis.Nanoseconds(), test.NumColors, test.IsSafe, test.Report.Status())
+			fmt.Printf("\tRounds: %d\tMessages: %d\tMaxNodeMessages: %d\tSeed: %d\n", test.Stats.Rounds, test.Stats.Messages, test.Stats.MaxNodeMessages, test.Seed)
+			if td.Init != "" {
+				fmt.Printf("\tInitColors: %d\tInitMaxColor: %d\n", test.InitColors, test.InitMaxColor)
+			}
+			printKWPhases(test, "\t")
+			printCVIterations(test, "\t")
+			if test.BadReps > 0 {
+				fmt.Printf("\tUnsafe or Over-Bound Reps: %d of %d, reporting the worst\n", test.BadReps, test.Timing.Reps())
+			}
+			if test.Timing.Reps() > 1 {
+				printTiming(test.Timing)
+			}
+			if test.Speedup > 0 {
+				fmt.Printf("\tWorkers: %d\tSpeedup: %.2f\tEfficiency: %.2f\n", test.Stats.Workers, test.Speedup, test.Efficiency)
+			}
 		}
 	}
 
 	fmt.Printf("\n-------------------------\n")
+	if len(failures) > 0 {
+		fmt.Printf("%d of %d directives failed:\n", len(failures), len(tds)+len(lineErrs))
+		for _, failure := range failures {
+			fmt.Printf("\t%s\n", failure)
+		}
+	}
+	if len(tResults) == 0 {
+		return
+	}
 	testOutName := extractTestName(testFileName)
 	g.GenerateHTMLForDataPoints(tResults, testOutName) //TODO: CHANGE GRAPH NAME
 	writeJson(tResults, testOutName)
 }
 
+// kwRoundBound returns the bound on a test's Kuhn-Wattenhofer merge rounds from the colors its first phase started with,
+// which for Linial are the colors left by its own reduction, or 0 if it ran no merge phases
+func kwRoundBound(test t.TestData) int {
+	if len(test.Stats.KWPhases) == 0 {
+		return 0
+	}
+	return r.KWRoundBound(test.Stats.KWPhases[0].BinsIn, test.Output.MaxDegree)
+}
+
+// printKWPhases prints every Kuhn-Wattenhofer merge phase of a test, and their rounds against the bound, each line led by indent
+func printKWPhases(test t.TestData, indent string) {
+	if len(test.Stats.KWPhases) == 0 {
+		return
+	}
+	mergeRounds := 0
+	for i, phase := range test.Stats.KWPhases {
+		fmt.Printf("%sKW Phase %d:\tBins: %d -> %d\tGroups: %d\tMax Inner Steps: %d\n", indent, i+1, phase.BinsIn, phase.BinsOut, phase.Groups, phase.MaxInnerSteps)
+		mergeRounds += phase.MaxInnerSteps
+	}
+	fmt.Printf("%sKW Merge Rounds: %d\tBound (MaxDegree+1)*phases: %d\n", indent, mergeRounds, kwRoundBound(test))
+}
+
+// printCVIterations prints the Cole-Vishkin iterations of a test against log* of its initial colors, led by indent
+func printCVIterations(test t.TestData, indent string) {
+	if test.Stats.CVIterations == 0 {
+		return
+	}
+	fmt.Printf("%sCV Iterations: %d\tlog*(k): %d for k = %d\n", indent, test.Stats.CVIterations, r.LogStar(float64(test.InitMaxColor)+1), test.InitMaxColor+1)
+}
+
+// printTiming prints the summary statistics of a repeated test
+func printTiming(timing t.Timing) {
+	fmt.Printf("\tReps: %d (+%d warmup)\tMin: %d\tMedian: %d\tMean: %d\tStdDev: %d\t95%% CI: [%d, %d]\n",
+		timing.Reps(), timing.Warmup, timing.Min, timing.Median, timing.Mean, timing.StdDev, timing.CILow, timing.CIHigh)
+}
+
+// listAlgorithms prints every registered algorithm with its ID, short ID and capabilities
+func listAlgorithms() {
+	for _, id := range r.AllAlgIds {
+		red, _ := r.Lookup(id)
+		fmt.Printf("%d\t%-8s\t%-24s\t%s\n", id, red.ShortID(), red.Capabilities(), red.Name())
+	}
+}
+
+// convertGraph reads a graph file in any supported format, or given as format:fileName, and writes it to outFileName in the format given by its extension:
+// DIMACS for .col, and DOT, GraphML or GEXF for the extensions g.WriteGraphFile accepts
+func convertGraph(inFileName string, outFileName string) error {
+	gr, report, err := t.ParseGraphFileWithReport(inFileName, false)
+	if err != nil {
+		return err
+	}
+	if !report.Clean() {
+		fmt.Printf("Read %s as %s\n", inFileName, report)
+	}
+	switch {
+	case strings.EqualFold(filepath.Ext(outFileName), ".col"):
+		return t.WriteDIMACSFile(&gr, outFileName)
+	case g.IsExportFile(outFileName):
+		return g.WriteGraphFile(&gr, outFileName)
+	default:
+		return fmt.Errorf("unsupported output format %s", filepath.Ext(outFileName))
+	}
+}
+
+// generateGraph builds a graph from a generator model and writes it to outFileName, in DIMACS format if it ends in .col
+func generateGraph(modelName string, outFileName string, args []string) error {
+	params, err := gen.ParseParams(args)
+	if err != nil {
+		return err
+	}
+	adj, err := gen.Generate(modelName, params)
+	if err != nil {
+		return err
+	}
+	if err := adj.WriteFile(outFileName); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s: %d nodes, %d edges, max degree %d\n", outFileName, len(adj.Neighbors), adj.NumEdges(), adj.MaxDegree())
+	return nil
+}
+
 // writeJson is a helper method to write an output to a json output file
 func writeJson(tResults map[int]g.DataPoint, testFileName string) {
 	b, err := json.Marshal(tResults)
@@ -0,0 +1,74 @@
+package reductions
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+/*
+	CONGEST-model bandwidth accounting
+		- Sizer: implemented by message payloads that know their own size in bits
+		- payloadBits: measures the size of a payload sent over the Network
+		- congestBitCap: the O(log n) per-edge, per-round bit budget
+*/
+
+// Sizer is implemented by message payloads that are not plain numbers, reporting how many bits they take on the wire
+type Sizer interface {
+	Bits() int
+}
+
+// congestBitCap returns the number of bits an edge may carry per round in CONGEST mode, factor * ceil(log2 n).
+// A factor <= 0 means the LOCAL model, where messages are unbounded, and returns 0.
+func congestBitCap(numNodes int, factor int) int {
+	if factor <= 0 {
+		return 0
+	}
+	logN := int(math.Ceil(math.Log2(float64(numNodes))))
+	if logN < 1 {
+		logN = 1
+	}
+	return factor * logN
+}
+
+// payloadBits measures a payload in bits. Integers take their bit length, so a color c costs O(log c) bits.
+// Payloads that are not numbers must implement Sizer, and anything else panics so that unmeasured sends cannot go unnoticed.
+func payloadBits(payload interface{}) int {
+	switch p := payload.(type) {
+	case Sizer:
+		return p.Bits()
+	case int:
+		return intBits(p)
+	case int32:
+		return intBits(int(p))
+	case int64:
+		return intBits(int(p))
+	case bool:
+		return 1
+	case float32:
+		return 32
+	case float64:
+		return 64
+	default:
+		panic(fmt.Sprintf("reductions: cannot measure payload of type %T, implement Sizer", payload))
+	}
+}
+
+// intBits is the number of bits needed to send an int, with one extra sign bit for negative values
+func intBits(x int) int {
+	if x < 0 {
+		return bits.Len(uint(-x)) + 1
+	}
+	if x == 0 {
+		return 1
+	}
+	return bits.Len(uint(x))
+}
+
+// splitRounds returns how many CONGEST rounds it takes to send msgBits over an edge with the given cap
+func splitRounds(msgBits int, bitCap int) int {
+	if bitCap <= 0 || msgBits <= bitCap {
+		return 1
+	}
+	return (msgBits + bitCap - 1) / bitCap
+}
@@ -4,15 +4,19 @@ import (
 	"fmt"
 	g "github.com/thomaseb191/go-coloring/graphs"
 	"log"
-	"math"
+	"math/bits"
 	"math/rand"
+	"sort"
+	"sync/atomic"
 )
 
 // A Forest is defined as a collection of disjoint trees, where each node is a ForestNode
+// Nodes holds only the Forest's own nodes, in order of their Ind in the original Graph, so a Forest takes memory for its edges alone.
+// Root is the Forest's node of lowest index, which is always the root of its tree
 type Forest struct {
 	ID    int
 	Root  *ForestNode
-	Nodes map[int]*ForestNode
+	Nodes []*ForestNode
 }
 
 // A ForestNode is a node in a Forest, including a Pointer to its relevant node in the original Graph, as well as color, tempcolor, and neighbor information
@@ -31,166 +35,203 @@ type ForestNode struct {
 //	Extra is the next most important value
 //	F is a pointer to a Forest if necessary
 //	Threshold is the level to which down shifting should occur
+//	IsTemp is whether the current iteration sets TempColor (from Color) rather than Color (from TempColor)
 type myChannelData struct {
 	Op        int
 	Val       int
 	Extra     int
 	F         *Forest
 	Threshold int
+	IsTemp    bool
 }
 
-//global variables to keep track of which color value to set and the number of nodes in the overall graph
-var isTemp bool
-var numAllNodes int
-
 // CVReduction is based on the Cole-Vishkin color reduction algorithm and is comprised of the following steps
 //		Creation of a worker pool of goroutines
-//		[Parallel] Decomposition into maxDegree Forests
+//		[Parallel] Decomposition into maxDegree Forests, already oriented
 //		[Parallel] CV Reduction of each forest into 6 colors
 //		[Parallel] Down-shifting of 6 colors into 3 colors
 //		Unification of the various forests into a MaxDegree+1 coloring
 // CVReduction is based on https://www.cs.bgu.ac.il/~elkinm/book.pdf and https://www.mpi-inf.mpg.de/fileadmin/inf/d1/teaching/winter15/tods/ToDS.pdf
-// It is described as having O(Delta^2) + logstar(n) runtime. Because of practical Forest Decomposition, however, our algorithm runs in O(Delta^2) + logstar(n) + O(n) time
-func CVReduction(gr g.Graph, poolSize int, debug int) g.Graph {
+// It is described as having O(Delta^2) + logstar(k) runtime for an initial coloring with colors below k, which is logstar(n) for index colors.
+// Because Forest Unification picks one node's color at a time, however, our algorithm runs in O(Delta^2) + logstar(k) + O(n) time
+// Rounds are recorded as if every Forest ran in parallel, since Forests are edge-disjoint
+// All state is scoped to a single call, so several reductions may run at once
+// For paths, cycles and trees, CVThreeColoring skips the decomposition and unification, see cvThreeColoring.go
+func CVReduction(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	debug := opts.Debug
+	net := NewNetwork(&gr, opts)
 	if debug%2 == 1 {
 		fmt.Printf("Starting CV Reduction \n")
 	}
-	isTemp = true
-	numAllNodes = len(gr.Nodes)
-	mainChannel := make(chan myChannelData)
-	channels := buildWorkers(gr, poolSize, mainChannel, debug)
+	isTemp := true
+	seed := runSeed(opts)
 
 	if debug%2 == 1 {
 		fmt.Printf("\tStarting Forest Decomposition \n")
 	}
 
-	forests := forestDecomposition(gr, channels, mainChannel, debug)
+	forests := forestDecomposition(&gr, net.Pool(), net, seed)
+	net.AddRounds(1)
 	for _, f := range forests {
-		for _, node := range f.Nodes {
-			bfsForest(node)
-		}
-		// Cannot be made parallel for bfs
+		recordForest(f, net, fmt.Sprintf("forest %d", f.ID), 0, false)
 	}
 
+	mainChannel := make(chan myChannelData)
+	channels := buildWorkers(gr, net.Pool(), mainChannel, net, seed, debug)
+
 	if debug%2 == 1 {
 		fmt.Printf("\tStarting CV to 6 \n")
 	}
 
+	// Every node knows the largest initial color, so every Forest runs the same number of iterations
+	iterations := cvIterations(maxColor(&gr))
+	cvRounds := 0
 	for _, f := range forests {
-		cvForestTo6(f, channels, mainChannel, debug)
-		shiftDown(f, channels, mainChannel, debug)
+		isTemp = cvForestTo6(f, channels, mainChannel, isTemp, iterations, net, debug)
+		isTemp = shiftDown(f, channels, mainChannel, isTemp, iterations, net, debug)
 		//printForest(f)
+		cvRounds = iterations + 6
 	}
+	net.AddRounds(cvRounds)
+	stopWorkers(channels)
 
 	if debug%2 == 1 {
 		fmt.Printf("\tStarting Forest Unification \n")
 	}
 
-	unifyForests2(forests, &gr)
-	return gr
+	unifyForests2(forests, &gr, net, newStreamRand(seed, 0))
+	stats := net.Stats()
+	stats.CVIterations = iterations
+	return gr, stats
 }
 
-// forestDecomposition is the leader implementation of Forest Decomposition
-func forestDecomposition(gr g.Graph, c []chan myChannelData, mainChan chan myChannelData, debug int) []*Forest {
-	op := 0
-	numDone := 0
-	numChannels := len(c)
-
-	myForests := make([]*Forest, gr.MaxDegree)
-	for i := 0; i < gr.MaxDegree; i++ {
-		myForests[i] = &Forest{
-			ID:    i,
-			Nodes: make(map[int]*ForestNode),
+// forestDecomposition splits the edges of gr into MaxDegree Forests, based on the Panconesi and Rizzi Decomposition.
+// Every node puts each edge to a lower neighbor in a different Forest, starting from a random offset, and that neighbor
+// is its parent there. Parents always have a lower index, so every Forest comes already oriented, without rounds of its own.
+// Nodes bucket their edges by Forest on the Pool, and then each Forest is built from its own bucket by a single worker,
+// so no edge passes through a channel, memory stays O(m), and every run with the same seed builds the same Forests
+func forestDecomposition(gr *g.Graph, pool *Pool, net *Network, seed int64) []*Forest {
+	numForests := gr.MaxDegree
+	size := len(gr.Nodes)
+	starters := make([]int, size)
+	forestOf := func(v int, i int) int {
+		return (starters[v] + i) % len(gr.Nodes[v].Neighbors)
+	}
+
+	// starts[f+1] first counts the edges of Forest f, then becomes where they start in children and parents
+	starts := make([]int32, numForests+1)
+	pool.For(size, func(v int) {
+		currNode := gr.Nodes[v]
+		if len(currNode.Neighbors) == 0 {
+			// Isolated nodes belong to no forest
+			return
 		}
-	}
-
-	for i, ch := range c {
-		startingInd := len(gr.Nodes) / numChannels * i
-		endingInd := len(gr.Nodes) / numChannels * (i + 1)
-		if i == len(c)-1 {
-			endingInd = len(gr.Nodes)
+		net.Charge(currNode, len(currNode.Neighbors), intBits(currNode.Ind))
+		starters[v] = int(nodeRandom(seed, 0, v) % uint64(len(currNode.Neighbors)))
+		for i, n := range currNode.Neighbors {
+			if n.Ind < v {
+				atomic.AddInt32(&starts[forestOf(v, i)+1], 1)
+			}
 		}
-		ch <- myChannelData{
-			Op:    op,
-			Val:   startingInd,
-			Extra: endingInd,
+	})
+	for f := 1; f <= numForests; f++ {
+		starts[f] += starts[f-1]
+	}
+
+	// Every edge is written at the next free position of its Forest's bucket, as its child and its parent
+	next := append([]int32(nil), starts[:numForests]...)
+	children := make([]int32, starts[numForests])
+	parents := make([]int32, starts[numForests])
+	pool.For(size, func(v int) {
+		for i, n := range gr.Nodes[v].Neighbors {
+			if n.Ind < v {
+				pos := atomic.AddInt32(&next[forestOf(v, i)], 1) - 1
+				children[pos] = int32(v)
+				parents[pos] = int32(n.Ind)
+			}
 		}
-	}
+	})
+
+	forests := make([]*Forest, numForests)
+	pool.For(numForests, func(f int) {
+		bucket := forestEdges{children[starts[f]:starts[f+1]], parents[starts[f]:starts[f+1]]}
+		// Buckets fill in whatever order the workers ran, and a node has at most one parent per Forest
+		sort.Sort(bucket)
+		forests[f] = buildForest(f, gr, bucket)
+	})
+	return forests
+}
 
-	for numDone < numChannels {
-		rec := <-mainChan
-		if rec.Op == -1 {
-			numDone++
-		} else {
-			forestToAdd := rec.Op
-			parent := gr.Nodes[rec.Val]
-			child := gr.Nodes[rec.Extra]
-			fPtr := myForests[forestToAdd]
-
-			existingC, ok := fPtr.Nodes[child.Ind]
-			if !ok {
-				newChild := ForestNode{
-					Pointer:   child,
-					Color:     child.Color,
-					TempColor: child.Color,
-					Parent:    nil,
-					Neighbors: make([]*ForestNode, 0),
-				}
-				fPtr.Nodes[child.Ind] = &newChild
-				existingC = &newChild
-			}
+// forestEdges is the bucket of a Forest's edges, each from a child to its parent, sortable by child
+type forestEdges struct {
+	children []int32
+	parents  []int32
+}
 
-			existingP, ok := fPtr.Nodes[parent.Ind]
-			if !ok {
-				newParent := ForestNode{
-					Pointer:   parent,
-					Color:     parent.Color,
-					TempColor: parent.Color,
-					Parent:    nil,
-					Neighbors: []*ForestNode{existingC},
-				}
-				fPtr.Nodes[parent.Ind] = &newParent
-				existingP = &newParent
-			}
-			existingC.Neighbors = append(existingC.Neighbors, existingP)
+func (e forestEdges) Len() int           { return len(e.children) }
+func (e forestEdges) Less(i, j int) bool { return e.children[i] < e.children[j] }
+func (e forestEdges) Swap(i, j int) {
+	e.children[i], e.children[j] = e.children[j], e.children[i]
+	e.parents[i], e.parents[j] = e.parents[j], e.parents[i]
+}
 
-			if fPtr.Root == nil {
-				fPtr.Root = existingP
-			}
+// buildForest builds the Forest of the given edges, sorted by child, giving every node its Graph node's color
+func buildForest(id int, gr *g.Graph, edges forestEdges) *Forest {
+	byInd := make(map[int32]*ForestNode, 2*edges.Len())
+	nodeFor := func(ind int32) *ForestNode {
+		if existing, ok := byInd[ind]; ok {
+			return existing
+		}
+		node := &ForestNode{
+			Pointer:   gr.Nodes[ind],
+			Color:     gr.Nodes[ind].Color,
+			TempColor: gr.Nodes[ind].Color,
 		}
+		byInd[ind] = node
+		return node
+	}
+	for i, v := range edges.children {
+		child := nodeFor(v)
+		parent := nodeFor(edges.parents[i])
+		child.Parent = parent
+		child.Neighbors = append(child.Neighbors, parent)
+		parent.Neighbors = append(parent.Neighbors, child)
+	}
+
+	forest := &Forest{
+		ID:    id,
+		Nodes: make([]*ForestNode, 0, len(byInd)),
 	}
-	return myForests
+	for _, node := range byInd {
+		forest.Nodes = append(forest.Nodes, node)
+	}
+	sort.Slice(forest.Nodes, func(i, j int) bool { return forest.Nodes[i].Pointer.Ind < forest.Nodes[j].Pointer.Ind })
+	if len(forest.Nodes) > 0 {
+		forest.Root = forest.Nodes[0]
+	}
+	return forest
 }
 
-// forestDecompositionWorker is the worker implementation of Forest Decomposition based on the Panconesi and Rizzi Decomposition
-// Each edge that is divided into a forest is reported to the main thread
-func forestDecompositionWorker(gr *g.Graph, startingInd int, endingInd int, mainChannel chan myChannelData) {
-	for k := startingInd; k < endingInd && k < len(gr.Nodes); k++ {
-		currNode := gr.Nodes[k]
-		starter := rand.Intn(len(currNode.Neighbors))
-		for i, n := range currNode.Neighbors {
-			if n.Ind < currNode.Ind {
-				forest := (starter + i) % len(currNode.Neighbors)
-				mainChannel <- myChannelData{
-					Op:    forest,
-					Val:   currNode.Ind,
-					Extra: n.Ind,
-				}
-			}
+// forestMemberships lists every node's ForestNodes by its Ind, in Forest order, so a node finds its Forests without searching all of them
+func forestMemberships(forests []*Forest, size int) [][]*ForestNode {
+	memberships := make([][]*ForestNode, size)
+	for _, f := range forests {
+		for _, node := range f.Nodes {
+			memberships[node.Pointer.Ind] = append(memberships[node.Pointer.Ind], node)
 		}
 	}
-	mainChannel <- myChannelData{
-		Op: -1,
-	}
+	return memberships
 }
 
 // cvForestTo6 is the leader implementation of CV for a given Forest
-func cvForestTo6(f *Forest, c []chan myChannelData, mainChan chan myChannelData, debug int) {
+// It runs the given number of iterations, see cvIterations.
+// isTemp is whether the first iteration sets TempColor, and the value for the iteration after the last is returned
+func cvForestTo6(f *Forest, c []chan myChannelData, mainChan chan myChannelData, isTemp bool, iterations int, net *Network, debug int) bool {
 	op := 1
 
 	numChannels := len(c)
 
-	for i := 0; i < logStar(float64(len(f.Nodes)))+3; i++ {
+	for i := 0; i < iterations; i++ {
 		numDone := 0
 		for k, ch := range c {
 			startingInd := k
@@ -198,8 +239,9 @@ func cvForestTo6(f *Forest, c []chan myChannelData, mainChan chan myChannelData,
 			ch <- myChannelData{
 				Op:    op + i%2, //1 if set to TempColor, 2 if set to Color
 				Val:   startingInd,
-				Extra: step,
-				F:     f,
+				Extra:  step,
+				F:      f,
+				IsTemp: isTemp,
 			}
 		}
 		for numDone < numChannels {
@@ -208,35 +250,43 @@ func cvForestTo6(f *Forest, c []chan myChannelData, mainChan chan myChannelData,
 				numDone++
 			}
 		}
+		recordForest(f, net, fmt.Sprintf("cv forest %d", f.ID), i+1, isTemp)
 		isTemp = !isTemp
 	}
+	return isTemp
 }
 
 //cvForestTo6Worker is the worker implementation of Cole-Vishkin, setting the new color (either Color or TempColor) accordingly
-func cvForestTo6Worker(f *Forest, startingInd int, step int, mainChannel chan myChannelData) {
+func cvForestTo6Worker(f *Forest, startingInd int, step int, isTemp bool, net *Network, mainChannel chan myChannelData) {
 	//change from previous iteration allows for more likelihood that each channel will have valid work to do
-	for k := startingInd; k <= numAllNodes; k += step {
-		currNode, ok := f.Nodes[k]
-		if ok {
-			parent := currNode.Parent
-			if parent == nil {
-				if isTemp {
-					currNode.TempColor = calcColorRoot(currNode.Color)
-				} else {
-					currNode.Color = calcColorRoot(currNode.TempColor)
+	for k := startingInd; k < len(f.Nodes); k += step {
+		currNode := f.Nodes[k]
+		parent := currNode.Parent
+		if parent == nil {
+			if isTemp {
+				currNode.TempColor = calcColorRoot(currNode.Color)
+			} else {
+				currNode.Color = calcColorRoot(currNode.TempColor)
+			}
+		} else {
+			// Only read the field the parent is not writing this iteration
+			var sentColor int
+			if isTemp {
+				sentColor = parent.Color
+			} else {
+				sentColor = parent.TempColor
+			}
+			net.Charge(parent.Pointer, 1, intBits(sentColor))
+			if isTemp {
+				if currNode.Color == parent.Color {
+					log.Fatalf("me and parent is temp same! %d, %s, %s, %t\n%d, %d\n", currNode.Color, currNode.Pointer.Name, parent.Pointer.Name, parent.Parent == nil, currNode.TempColor, parent.TempColor)
 				}
+				currNode.TempColor = calcColor(currNode.Color, parent.Color)
 			} else {
-				if isTemp {
-					if currNode.Color == parent.Color {
-						log.Fatalf("me and parent is temp same! %d, %s, %s, %t\n%d, %d\n", currNode.Color, currNode.Pointer.Name, parent.Pointer.Name, parent.Parent == nil, currNode.TempColor, parent.TempColor)
-					}
-					currNode.TempColor = calcColor(currNode.Color, parent.Color)
-				} else {
-					if currNode.TempColor == parent.TempColor {
-						log.Fatalf("me and parent is same! %d, %s, %s, %t\n", currNode.TempColor, currNode.Pointer.Name, parent.Pointer.Name, parent.Parent == nil)
-					}
-					currNode.Color = calcColor(currNode.TempColor, parent.TempColor)
+				if currNode.TempColor == parent.TempColor {
+					log.Fatalf("me and parent is same! %d, %s, %s, %t\n", currNode.TempColor, currNode.Pointer.Name, parent.Pointer.Name, parent.Parent == nil)
 				}
+				currNode.Color = calcColor(currNode.TempColor, parent.TempColor)
 			}
 		}
 	}
@@ -246,7 +296,8 @@ func cvForestTo6Worker(f *Forest, startingInd int, step int, mainChannel chan my
 }
 
 // shiftDown is the leader implementation of down shifting process to reduce 6-color Forests to 3-color Forests
-func shiftDown(f *Forest, c []chan myChannelData, mainChan chan myChannelData, debug int) {
+// Like cvForestTo6, it takes and returns whether the next iteration sets TempColor. cvRounds is the number of CV iterations before it
+func shiftDown(f *Forest, c []chan myChannelData, mainChan chan myChannelData, isTemp bool, cvRounds int, net *Network, debug int) bool {
 	op := 3
 
 	numChannels := len(c)
@@ -259,8 +310,9 @@ func shiftDown(f *Forest, c []chan myChannelData, mainChan chan myChannelData, d
 			ch <- myChannelData{
 				Op:    op + i%2, //3 if set to TempColor, 4 if set to Color
 				Val:   startingInd,
-				Extra: step,
-				F:     f,
+				Extra:  step,
+				F:      f,
+				IsTemp: isTemp,
 			}
 		}
 		for numDone < numChannels {
@@ -280,14 +332,27 @@ func shiftDown(f *Forest, c []chan myChannelData, mainChan chan myChannelData, d
 				Extra:     step,
 				Threshold: 6 - i,
 				F:         f,
+				IsTemp:    isTemp,
 			}
 		}
+		// Workers report new colors instead of writing them, so every node decides on its neighbors' colors from before this stage
+		var updates []myChannelData
 		for numDone < numChannels {
 			rec := <-mainChan
 			if rec.Op == -1 {
 				numDone++
+			} else {
+				updates = append(updates, rec)
 			}
 		}
+		for _, rec := range updates {
+			if isTemp {
+				f.Nodes[rec.Val].TempColor = rec.Extra
+			} else {
+				f.Nodes[rec.Val].Color = rec.Extra
+			}
+		}
+		recordForest(f, net, fmt.Sprintf("shift-down forest %d to %d colors", f.ID, 5-i), cvRounds+2*i+2, isTemp)
 		isTemp = !isTemp
 	}
 	for _, k := range f.Nodes {
@@ -295,35 +360,42 @@ func shiftDown(f *Forest, c []chan myChannelData, mainChan chan myChannelData, d
 			k.Color = k.TempColor
 		}
 	}
+	return isTemp
 }
 
 // shiftDownWorker is the worker implementation of the first stage of the down shift algorithm
-func shiftDownWorker(f *Forest, startingInd int, step int, mainChannel chan myChannelData) {
+func shiftDownWorker(f *Forest, startingInd int, step int, isTemp bool, net *Network, rng *rand.Rand, mainChannel chan myChannelData) {
 	//change from previous iteration allows for more likelihood that each channel will have valid work to do
-	for k := startingInd; k <= numAllNodes; k += step {
-		currNode, ok := f.Nodes[k]
-		if ok {
-			parent := currNode.Parent
-			if parent == nil {
-				if isTemp {
-					newColor := currNode.Color
-					for newColor == currNode.Color {
-						newColor = rand.Intn(3)
-					}
-					currNode.TempColor = newColor
-				} else {
-					newColor := currNode.TempColor
-					for newColor == currNode.TempColor {
-						newColor = rand.Intn(3)
-					}
-					currNode.Color = newColor
+	for k := startingInd; k < len(f.Nodes); k += step {
+		currNode := f.Nodes[k]
+		parent := currNode.Parent
+		if parent == nil {
+			if isTemp {
+				newColor := currNode.Color
+				for newColor == currNode.Color {
+					newColor = rng.Intn(3)
 				}
+				currNode.TempColor = newColor
 			} else {
-				if isTemp {
-					currNode.TempColor = parent.Color
-				} else {
-					currNode.Color = parent.TempColor
+				newColor := currNode.TempColor
+				for newColor == currNode.TempColor {
+					newColor = rng.Intn(3)
 				}
+				currNode.Color = newColor
+			}
+		} else {
+			// Only read the field the parent is not writing this iteration
+			var sentColor int
+			if isTemp {
+				sentColor = parent.Color
+			} else {
+				sentColor = parent.TempColor
+			}
+			net.Charge(parent.Pointer, 1, intBits(sentColor))
+			if isTemp {
+				currNode.TempColor = parent.Color
+			} else {
+				currNode.Color = parent.TempColor
 			}
 		}
 	}
@@ -332,18 +404,23 @@ func shiftDownWorker(f *Forest, startingInd int, step int, mainChannel chan myCh
 	}
 }
 
-// shiftDownWorker is the worker implementation of the second stage of the down shift algorithm
-func shiftDownWorkerCleanup(f *Forest, startingInd int, step int, thresh int, mainChannel chan myChannelData) {
+// shiftDownWorkerCleanup is the worker implementation of the second stage of the down shift algorithm.
+// Each changed color is reported to the leader with Op -2, Val set to the node's position in f.Nodes and Extra to its new color
+func shiftDownWorkerCleanup(f *Forest, startingInd int, step int, thresh int, isTemp bool, net *Network, rng *rand.Rand, mainChannel chan myChannelData) {
 	//change from previous iteration allows for more likelihood that each channel will have valid work to do
-	for k := startingInd; k <= numAllNodes; k += step {
-		currNode, ok := f.Nodes[k]
-		if ok {
-			if isTemp {
-				newColor := calcSafeReduction(currNode, thresh)
-				currNode.TempColor = newColor
-			} else {
-				newColor := calcSafeReduction(currNode, thresh)
-				currNode.Color = newColor
+	for k := startingInd; k < len(f.Nodes); k += step {
+		currNode := f.Nodes[k]
+		net.Charge(currNode.Pointer, len(currNode.Neighbors), intBits(currNode.Color))
+		oldColor := currNode.Color
+		if isTemp {
+			oldColor = currNode.TempColor
+		}
+		// Neighbors may be deciding at the same time, so the leader applies the new color once every worker is done
+		if newColor := calcSafeReduction(currNode, thresh, isTemp, rng); newColor != oldColor {
+			mainChannel <- myChannelData{
+				Op:    -2,
+				Val:   k,
+				Extra: newColor,
 			}
 		}
 	}
@@ -354,37 +431,28 @@ func shiftDownWorkerCleanup(f *Forest, startingInd int, step int, thresh int, ma
 
 // unifyForests is a deprecated first attempt at unifying separate Forests
 func unifyForests(forests []*Forest, gr *g.Graph) {
+	memberships := forestMemberships(forests, len(gr.Nodes))
 	for _, k := range gr.Nodes {
-		fPtr, ok := forests[0].Nodes[k.Ind]
-		if ok {
-			k.Color = fPtr.Color
-		} else {
-			k.Color = -1
-		}
-	}
-	for i := 1; i < len(forests); i++ {
-		for _, k := range gr.Nodes {
-			fPtr, ok := forests[i].Nodes[k.Ind]
-			if ok && k.Color != -1 {
+		k.Color = -1
+		for _, fPtr := range memberships[k.Ind] {
+			if k.Color != -1 {
 				//k.Color = calcColor(k.Color, fPtr.Color)
 				k.Color = k.Color | fPtr.Color
-			} else if ok {
-				k.Color = fPtr.Color
-			} else if k.Color != -1 {
-				//keep the same
 			} else {
-				k.Color = -1
+				k.Color = fPtr.Color
 			}
 		}
 	}
 }
 
 // unifyForests2 is the leader implementation (less efficient) to unify Forests
-func unifyForests2(forests []*Forest, gr *g.Graph) {
+// Nodes pick their colors one at a time, so this costs one round per node. rng is the leader's, see random.go
+func unifyForests2(forests []*Forest, gr *g.Graph, net *Network, rng *rand.Rand) {
+	memberships := forestMemberships(forests, len(gr.Nodes))
 	for _, k := range gr.Nodes {
 		k.Color = -1
 	}
-	for _, k := range gr.Nodes {
+	for idx, k := range gr.Nodes {
 		options := makeRange(0, gr.MaxDegree+1)
 		//handle colors already set
 		for _, i := range k.Neighbors {
@@ -396,23 +464,49 @@ func unifyForests2(forests []*Forest, gr *g.Graph) {
 			}
 		}
 		//handle colors not set
-		for _, j := range forests {
-			fPtr, ok := j.Nodes[k.Ind]
-			if ok {
-				for _, i := range fPtr.Neighbors {
-					if i.Pointer.Color == -1 {
-						indInOptions := findIndexOf(options, i.Color)
-						if indInOptions != -1 {
-							options = remove(options, indInOptions)
-						}
+		for _, fPtr := range memberships[k.Ind] {
+			for _, i := range fPtr.Neighbors {
+				if i.Pointer.Color == -1 {
+					indInOptions := findIndexOf(options, i.Color)
+					if indInOptions != -1 {
+						options = remove(options, indInOptions)
 					}
 				}
-			} else {
-				continue
 			}
 		}
-		k.Color = options[rand.Intn(len(options))]
+		k.Color = options[rng.Intn(len(options))]
+		net.Charge(k, len(k.Neighbors), intBits(k.Color))
+		if net.Recording() {
+			net.RecordColors("unify", idx+1, g.ColorsOf(gr), nil)
+		}
+	}
+	net.AddRounds(len(gr.Nodes))
+}
+
+// recordForest records the colors of a Forest's nodes, drawing only its edges. Nodes outside the Forest are shown uncolored.
+// useTemp selects TempColor over Color, for the iterations that write TempColor
+func recordForest(f *Forest, net *Network, phase string, roundOffset int, useTemp bool) {
+	if !net.Recording() {
+		return
+	}
+	colors := make([]int, len(net.gr.Nodes))
+	for i := range colors {
+		colors[i] = -1
+	}
+	edges := make([][2]int, 0, len(f.Nodes))
+	for _, node := range f.Nodes {
+		ind := node.Pointer.Ind
+		colors[ind] = node.Color
+		if useTemp {
+			colors[ind] = node.TempColor
+		}
+		for _, neighbor := range node.Neighbors {
+			if neighbor.Pointer.Ind > ind {
+				edges = append(edges, [2]int{ind, neighbor.Pointer.Ind})
+			}
+		}
 	}
+	net.RecordColors(phase, roundOffset, colors, edges)
 }
 
 // findIndexOf searches an array for the desired color, returning -1 if not found
@@ -426,46 +520,35 @@ func findIndexOf(options []int, color int) int {
 }
 
 // workerWait is the overall manager for workers, governing the division into different subalgorithms
-func workerWait(gr *g.Graph, c chan myChannelData, mainChannel chan myChannelData) {
+// Every worker draws from its own rng, so workers never share one
+func workerWait(gr *g.Graph, c chan myChannelData, mainChannel chan myChannelData, net *Network, rng *rand.Rand) {
 	rec := <-c
-	if rec.Op == 0 {
-		forestDecompositionWorker(gr, rec.Val, rec.Extra, mainChannel)
-	} else {
-		log.Fatal("Wrong op received by worker")
-	}
-
-	rec = <-c
 	for rec.Op < 7 {
 		if rec.Op == 1 || rec.Op == 2 {
-			cvForestTo6Worker(rec.F, rec.Val, rec.Extra, mainChannel)
+			cvForestTo6Worker(rec.F, rec.Val, rec.Extra, rec.IsTemp, net, mainChannel)
 		} else if rec.Op == 3 || rec.Op == 4 {
-			shiftDownWorker(rec.F, rec.Val, rec.Extra, mainChannel)
+			shiftDownWorker(rec.F, rec.Val, rec.Extra, rec.IsTemp, net, rng, mainChannel)
 		} else {
-			shiftDownWorkerCleanup(rec.F, rec.Val, rec.Extra, rec.Threshold, mainChannel)
+			shiftDownWorkerCleanup(rec.F, rec.Val, rec.Extra, rec.Threshold, rec.IsTemp, net, rng, mainChannel)
 		}
 		rec = <-c
 	}
 
 }
 
-// logStar is the logstar function defined in the CV paper https://www.cs.bgu.ac.il/~elkinm/book.pdf
-func logStar(n float64) int {
-	if n <= 2 {
-		return 0
-	} else {
-		return 1 + logStar(math.Log2(n))
+// stopWorkers ends every worker's workerWait loop, so no goroutines outlive the reduction
+func stopWorkers(c []chan myChannelData) {
+	for _, ch := range c {
+		ch <- myChannelData{
+			Op: 7,
+		}
 	}
 }
 
-// buildWorkers creates a desired number of workers based on input specifications or a default
-func buildWorkers(gr g.Graph, poolSize int, mainChannel chan myChannelData, debug int) []chan myChannelData {
-	defaultPool := math.Floor(math.Sqrt(float64(len(gr.Nodes)))) //TODO: ADJUST DEFAULT AS NECESSARY
-	var numWorkers int
-	if poolSize <= 0 {
-		numWorkers = int(defaultPool)
-	} else {
-		numWorkers = int(math.Min(float64(poolSize), defaultPool))
-	}
+// buildWorkers creates one long-lived worker per worker of the Pool, so CV runs as many goroutines as every other algorithm
+// Worker i draws from stream i+1 of seed, leaving stream 0 to the leader
+func buildWorkers(gr g.Graph, pool *Pool, mainChannel chan myChannelData, net *Network, seed int64, debug int) []chan myChannelData {
+	numWorkers := pool.Size()
 
 	if debug%2 == 1 {
 		fmt.Printf("\tBuilding %d workers for %d nodes \n", numWorkers, len(gr.Nodes))
@@ -475,84 +558,42 @@ func buildWorkers(gr g.Graph, poolSize int, mainChannel chan myChannelData, debu
 	for i := 0; i < numWorkers; i++ {
 		c := make(chan myChannelData)
 		myChannels = append(myChannels, c)
-		go workerWait(&gr, c, mainChannel)
+		go workerWait(&gr, c, mainChannel, net, newStreamRand(seed, i+1))
 	}
 	return myChannels
 }
 
-// bfsForest runs a BFS on a given node, orienting the tree and setting parents. Cannot safely be run in parallel
-func bfsForest(f *ForestNode) {
-	if f != nil {
-		for _, k := range f.Neighbors {
-			if k.Parent == nil && f.Parent != k {
-				k.Parent = f
-				bfsForest(k)
-			}
-		}
-	}
-}
-
-// calcColor is the crux of the CV algorithm
-// implementation borrowed from https://www.zhengqunkoo.com:8443/zhengqunkoo/site/src/commit/ebbab6e24911a02c97b380f2e39f06d9c3e83770/worker.js
+// calcColor is the crux of the CV algorithm: a node's new color is 2i + b, where i is the lowest bit at which
+// its color differs from its parent's and b is its own bit there. A node and its parent either differ in i,
+// or share i and so differ in b, so the new coloring is still proper.
+// Colors are read as uint64, so any non-negative int color works, such as an ID of up to 62 bits
 func calcColor(me int, parent int) int {
-	me1 := uint32(me)
-	parent1 := uint32(parent)
 	if me == parent {
 		log.Fatal("me and parent is same! ", me, parent)
 	}
-
-	j := getDifferBitIndex(me1, parent1)
-	//midx := getBitLength(me1) - j - 1
-	midx := 32 - j - 1
-	return int((j << 1) | (me1&(1<<midx))>>midx)
+	i := bits.TrailingZeros64(uint64(me) ^ uint64(parent))
+	return 2*i + int(uint64(me)>>uint(i)&1)
 }
 
-// getDifferBitIndex returns the bit index from the left at which 2 uint32s vary (Big Endian)
-func getDifferBitIndex(x, y uint32) uint32 {
-	if x == y {
-		log.Fatal("No differing bit")
-	} else {
-		bl := uint32(32)
-		m := uint32(1) << (bl - 1)
-		var idx = 0
-		for m != 0 {
-			if (x & m) != (y & m) {
-				return uint32(idx)
-			}
-			m = m >> 1
-			idx++
-		}
-	}
-	log.Fatal("Why is index returned 0?")
-	return 0
+// calcColorRoot is the same as calcColor for a root, which has no parent and so always uses bit 0.
+// Its children that differ from it at bit 0 take a different b, and all others a larger i
+func calcColorRoot(me int) int {
+	return int(uint64(me) & 1)
 }
 
-// getBitLength is a function that returns the bit length of a number. Deprecated in favor of uint32 standardization
-func getBitLength(x uint32) uint32 {
-	// Minimum bit length is 3
-	if x < 6 {
-		return 3
-	} else {
-		var length = 0
-		for x != 0 {
-			x = x >> 1
-			length++
-		}
-		return uint32(length)
+// cvIterations returns the number of CV iterations that bring colors below maxColor+1 down to the 6 colors [0, 6).
+// Colors below 2^b become colors below 2b, so this is log*(maxColor) + O(1)
+func cvIterations(maxColor int) int {
+	iterations := 0
+	for limit := uint64(maxColor) + 1; limit > 6; iterations++ {
+		limit = 2 * uint64(bits.Len64(limit-1))
 	}
-}
-
-// calcColorRoot is the same as calcColor but uses an arbitrary index of 30 (0-indexed)
-func calcColorRoot(me int) int {
-	me1 := uint32(me)
-	j := uint32(30)
-	//midx := getBitLength(me1) - j - 1
-	midx := 32 - j - 1
-	return int((j << 1) | (me1&(1<<midx))>>midx)
+	return iterations
 }
 
 // calcSafeReduction is used to calculate a safe color to set during the down shifting process
-func calcSafeReduction(n *ForestNode, thresh int) int {
+// isTemp selects whether TempColor or Color is being reduced
+func calcSafeReduction(n *ForestNode, thresh int, isTemp bool, rng *rand.Rand) int {
 	if isTemp {
 		if n.TempColor < thresh {
 			return n.TempColor
@@ -560,11 +601,11 @@ func calcSafeReduction(n *ForestNode, thresh int) int {
 		var colorProposal int
 		safe := false
 		for !safe {
-			colorProposal = rand.Intn(3)
+			colorProposal = rng.Intn(3)
 			for i, _ := range n.Neighbors {
 				if n.Neighbors[i].TempColor == colorProposal {
 					i = 0 //reset the progress
-					colorProposal = rand.Intn(3)
+					colorProposal = rng.Intn(3)
 				}
 			}
 			safe = true
@@ -577,11 +618,11 @@ func calcSafeReduction(n *ForestNode, thresh int) int {
 		var colorProposal int
 		safe := false
 		for !safe {
-			colorProposal = rand.Intn(3)
+			colorProposal = rng.Intn(3)
 			for i, _ := range n.Neighbors {
 				if n.Neighbors[i].Color == colorProposal {
 					i = 0 //reset the progress
-					colorProposal = rand.Intn(3)
+					colorProposal = rng.Intn(3)
 				}
 			}
 			safe = true
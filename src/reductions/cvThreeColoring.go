@@ -0,0 +1,239 @@
+package reductions
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"math"
+)
+
+/*
+	Cole-Vishkin 3-coloring of paths, cycles and rooted forests
+		- CVThreeColoring: colors a graph of paths, cycles or trees with at most 3 colors in log*(k) + O(1) rounds
+		- DetectTopology: finds whether every component of a graph is a path, a cycle or a tree
+		- LogStar: the iterated logarithm that the number of CV iterations is checked against
+	This is the classic setting of Cole-Vishkin: every node already knows its parent, so there is no Forest Decomposition
+	to run and no Forests to unify. The orientation is taken as part of the input, and is computed here without charging rounds:
+	trees hang from their node of lowest index, and cycles point from each node to the next along the cycle.
+*/
+
+const (
+	// TopologyPath is a graph whose every component is a path
+	TopologyPath = "path"
+	// TopologyCycle is a graph whose every component is a path or a cycle, so whose max degree is at most 2
+	TopologyCycle = "cycle"
+	// TopologyForest is a graph whose every component is a tree
+	TopologyForest = "forest"
+)
+
+// IsTopology returns whether name is a topology CVThreeColoring can be told its input has
+func IsTopology(name string) bool {
+	return name == TopologyPath || name == TopologyCycle || name == TopologyForest
+}
+
+// LogStar returns log*(x), the number of times log2 must be applied to x before it is at most 1
+func LogStar(x float64) int {
+	iterations := 0
+	for x > 1 {
+		x = math.Log2(x)
+		iterations++
+	}
+	return iterations
+}
+
+// DetectTopology returns the most specific topology of a graph, path before cycle before forest, or "" if it has none
+func DetectTopology(gr *g.Graph) string {
+	_, topology := orientTopology(gr)
+	return topology
+}
+
+// fitsTopology returns whether a graph detected as detected may be colored as told, since a path is also a cycle or a forest
+func fitsTopology(detected string, told string) bool {
+	switch told {
+	case TopologyPath:
+		return detected == TopologyPath
+	case TopologyCycle:
+		return detected == TopologyPath || detected == TopologyCycle
+	case TopologyForest:
+		return detected == TopologyPath || detected == TopologyForest
+	}
+	return false
+}
+
+// orientTopology gives every node of gr a parent, by index, or -1 for the root of a tree, and returns the graph's topology.
+// Each component is walked from its node of lowest index: a tree is oriented towards that node by BFS, and a cycle
+// from every node to the next one along it. If some component is neither, it returns nil and ""
+func orientTopology(gr *g.Graph) ([]int, string) {
+	size := len(gr.Nodes)
+	parent := make([]int, size)
+	visited := make([]bool, size)
+	maxDegree := 0
+	hasCycle := false
+	for s := 0; s < size; s++ {
+		if visited[s] {
+			continue
+		}
+		component := []int{s}
+		visited[s] = true
+		parent[s] = -1
+		degreeSum := 0
+		for i := 0; i < len(component); i++ {
+			v := gr.Nodes[component[i]]
+			degreeSum += len(v.Neighbors)
+			if len(v.Neighbors) > maxDegree {
+				maxDegree = len(v.Neighbors)
+			}
+			for _, neighbor := range v.Neighbors {
+				if !visited[neighbor.Ind] {
+					visited[neighbor.Ind] = true
+					parent[neighbor.Ind] = v.Ind
+					component = append(component, neighbor.Ind)
+				}
+			}
+		}
+		if degreeSum/2 == len(component)-1 {
+			continue
+		}
+		for _, v := range component {
+			if len(gr.Nodes[v].Neighbors) != 2 {
+				return nil, ""
+			}
+		}
+		hasCycle = true
+		prev, curr := -1, s
+		for {
+			next := gr.Nodes[curr].Neighbors[0].Ind
+			if next == prev {
+				next = gr.Nodes[curr].Neighbors[1].Ind
+			}
+			parent[curr] = next
+			prev, curr = curr, next
+			if curr == s {
+				break
+			}
+		}
+	}
+	switch {
+	case maxDegree <= 2 && !hasCycle:
+		return parent, TopologyPath
+	case maxDegree <= 2:
+		return parent, TopologyCycle
+	case !hasCycle:
+		return parent, TopologyForest
+	}
+	return nil, ""
+}
+
+// CVThreeColoring is Cole-Vishkin on a graph whose every component is a path, cycle or tree, detected or given as opts.Topology.
+//		Each of cvIterations(k) iterations halves the bits of every color against the node's parent, leaving 6 colors
+//		Colors 5, 4 and 3 are then removed one per step. Nodes of max degree 2 recolor directly, while trees first shift
+//		every color down to the children, so a node's children share a single color and it sees at most 2 colors around it
+// It takes log*(k) + 3 rounds on paths and cycles, log*(k) + 6 on trees, and leaves at most 3 colors.
+// The number of iterations is reported as RunStats.CVIterations, to check against LogStar(k).
+// A graph that is not a path, cycle or forest, or not the topology it is told it is, is reduced by CVReduction instead,
+// with RunStats.Fallback set so its results are never taken for a 3-coloring
+func CVThreeColoring(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	parent, topology := orientTopology(&gr)
+	if topology == "" || (opts.Topology != "" && !fitsTopology(topology, opts.Topology)) {
+		if opts.Debug%2 == 1 {
+			fmt.Printf("%s is not a %s, running the full Cole-Vishkin reduction\n", gr.Name, topologyName(opts.Topology))
+		}
+		outGraph, stats := CVReduction(gr, opts)
+		stats.Fallback = "cv"
+		return outGraph, stats
+	}
+	if opts.Debug%2 == 1 {
+		fmt.Printf("Starting CV 3-coloring of a %s \n", topology)
+	}
+	net := NewNetwork(&gr, opts)
+	pool := net.Pool()
+	size := len(gr.Nodes)
+
+	children := make([]int, size)
+	color := make([]int, size)
+	next := make([]int, size)
+	for v, node := range gr.Nodes {
+		color[v] = node.Color
+		if parent[v] >= 0 {
+			children[parent[v]]++
+		}
+	}
+	record := func(phase string) {
+		net.AddRounds(1)
+		if net.Recording() {
+			net.RecordColors(phase, 0, append([]int(nil), color...), nil)
+		}
+	}
+
+	// Every node sends its color to its children, which each take the lowest bit they differ from it at
+	iterations := cvIterations(maxColor(&gr))
+	for i := 0; i < iterations; i++ {
+		pool.For(size, func(v int) {
+			if p := parent[v]; p >= 0 {
+				next[v] = calcColor(color[v], color[p])
+			} else {
+				next[v] = calcColorRoot(color[v])
+			}
+			net.ChargeInd(v, children[v], intBits(color[v]))
+		})
+		color, next = next, color
+		record(fmt.Sprintf("cv iteration %d", i+1))
+	}
+
+	shift := topology == TopologyForest
+	for c := 5; c >= 3; c-- {
+		if shift {
+			// Every node takes its parent's color and every root a new one of 0, 1 and 2, so all children of a node match
+			pool.For(size, func(v int) {
+				if p := parent[v]; p >= 0 {
+					next[v] = color[p]
+				} else {
+					next[v] = (color[v] + 1) % 3
+				}
+				net.ChargeInd(v, children[v], intBits(color[v]))
+			})
+			color, next = next, color
+			record(fmt.Sprintf("shift down to %d colors", c+1))
+		}
+		// The nodes of color c are an independent set, so they all pick a free color of 0, 1 and 2 at once
+		pool.For(size, func(v int) {
+			next[v] = color[v]
+			if color[v] == c {
+				next[v] = freeOfThree(gr.Nodes[v], color)
+			}
+			net.ChargeInd(v, len(gr.Nodes[v].Neighbors), intBits(color[v]))
+		})
+		color, next = next, color
+		record(fmt.Sprintf("recolor to %d colors", c))
+	}
+
+	for v, node := range gr.Nodes {
+		node.Color = color[v]
+	}
+	stats := net.Stats()
+	stats.CVIterations = iterations
+	return gr, stats
+}
+
+// freeOfThree returns the lowest of the colors 0, 1 and 2 that no neighbor of v holds, or -1 if they all do
+func freeOfThree(v *g.Node, color []int) int {
+	var used [3]bool
+	for _, neighbor := range v.Neighbors {
+		if c := color[neighbor.Ind]; c >= 0 && c < 3 {
+			used[c] = true
+		}
+	}
+	for c, taken := range used {
+		if !taken {
+			return c
+		}
+	}
+	return -1
+}
+
+// topologyName names a topology for messages, where "" means any of them
+func topologyName(topology string) string {
+	if topology == "" {
+		return "path, cycle or forest"
+	}
+	return topology
+}
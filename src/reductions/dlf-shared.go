@@ -4,9 +4,8 @@ import (
 	"fmt"
 	s "github.com/goombaio/orderedset"
 	g "github.com/thomaseb191/go-coloring/graphs"
-	"math/rand"
 	"sync"
-	"time"
+	"sync/atomic"
 )
 
 type messageShared struct {
@@ -15,132 +14,199 @@ type messageShared struct {
 	avail  *s.OrderedSet
 }
 
-var data = make(map[string]messageShared)
-
-func dlfShared(gr g.Graph, poolSize int, debug int) g.Graph {
-	var wg sync.WaitGroup
-	wg.Add(len(gr.Nodes))
-
-	var checkpoint1 sync.WaitGroup
-	var checkpoint2 sync.WaitGroup
-	var checkpoint3 sync.WaitGroup
+// Bits counts the whole message as it is shared with neighbors, including the palette of available colors.
+// Since the palette holds up to MaxDegree+1 colors, dlfShared is not CONGEST-compliant for large degrees.
+func (m messageShared) Bits() int {
+	size := intBits(m.degree) + 32
+	for _, color := range m.avail.Values() {
+		size += intBits(color.(int))
+	}
+	return size
+}
 
-	checkpoint1.Add(len(gr.Nodes))
+// dlfShared runs Distributed Largest-First with every node sharing its state through a common slice indexed by Ind.
+// Each iteration is a LOCAL round in which every uncolored node sends its degree and random value to its neighbors.
+// The uncolored nodes are stepped on the Network's Pool in three phases per iteration, each ending in a barrier:
+// every node draws its random value, then every node decides whether it beats all its uncolored neighbors, then the winners take their colors.
+// The shared state belongs to a single call, so several runs may happen at once, even on graphs with the same node names.
+func dlfShared(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	debug := opts.Debug
+	net := NewNetwork(&gr, opts)
+	pool := net.Pool()
+	seed := runSeed(opts)
 
 	var lock sync.Mutex
+	data := make([]messageShared, len(gr.Nodes))
+	selected := make([]int, len(gr.Nodes))
+	won := make([]bool, len(gr.Nodes))
 
 	for _, node := range gr.Nodes {
-		if debug%2 == 1 {
-			fmt.Println(node.Name)
+		set := s.NewOrderedSet()
+		for i := 0; i <= gr.MaxDegree; i++ {
+			set.Add(i)
+		}
+		data[node.Ind] = messageShared{
+			degree: len(node.Neighbors),
+			rndval: -1,
+			avail:  set,
 		}
-		node := node
-		go func() {
-			vertexShared(node, gr.MaxDegree, &checkpoint1, &checkpoint2, &checkpoint3, &lock, debug)
-			wg.Done()
-		}()
 	}
 
-	wg.Wait()
-
-	return gr
-}
+	uncolored := make([]*g.Node, len(gr.Nodes))
+	copy(uncolored, gr.Nodes)
+	iterations := 0
+	for len(uncolored) > 0 {
+		if debug%2 == 1 {
+			fmt.Println("round:", iterations, "uncolored:", len(uncolored))
+		}
+		pool.For(len(uncolored), func(i int) {
+			n := uncolored[i]
+			data[n.Ind].rndval = nodeFloat32(seed, iterations, n.Ind)
+			selected[n.Ind] = data[n.Ind].avail.Values()[0].(int)
+			net.Charge(n, len(n.Neighbors), data[n.Ind].Bits())
+		})
+		pool.For(len(uncolored), func(i int) {
+			n := uncolored[i]
+			won[n.Ind] = beatsNeighborsShared(n, data, debug)
+		})
+		pool.For(len(uncolored), func(i int) {
+			n := uncolored[i]
+			if !won[n.Ind] {
+				return
+			}
+			n.Color = selected[n.Ind]
+			lock.Lock()
+			for _, neighbor := range n.Neighbors {
+				data[neighbor.Ind].avail.Remove(n.Color)
+				if debug%2 == 1 {
+					fmt.Println(neighbor.Name, data[neighbor.Ind].avail.Values())
+				}
+			}
+			lock.Unlock()
+			data[n.Ind].degree = -1
+		})
 
-func vertexShared(n *g.Node, maxDegree int, checkpoint1 *sync.WaitGroup, checkpoint2 *sync.WaitGroup, checkpoint3 *sync.WaitGroup, lock *sync.Mutex, debug int) {
-	rand.Seed(time.Now().UnixNano())
-	degree := len(n.Neighbors)
+		remaining := uncolored[:0]
+		for _, n := range uncolored {
+			if !won[n.Ind] {
+				remaining = append(remaining, n)
+			}
+		}
+		uncolored = remaining
+		iterations++
+	}
+	net.AddRounds(iterations)
 
-	set := s.NewOrderedSet()
+	return gr, net.Stats()
+}
 
-	for i := 0; i <= maxDegree; i++ {
-		set.Add(i)
+// beatsNeighborsShared returns whether an uncolored node beats every uncolored neighbor on degree, then random value.
+// Colored neighbors have a degree of -1 in data and are skipped
+func beatsNeighborsShared(n *g.Node, data []messageShared, debug int) bool {
+	m := data[n.Ind]
+	for _, neighbor := range n.Neighbors {
+		incmsg := data[neighbor.Ind]
+		if debug%2 == 1 {
+			fmt.Println(n.Name, incmsg.degree, incmsg.rndval)
+		}
+		if incmsg.degree == -1 {
+			continue
+		}
+		if incmsg.degree < m.degree {
+			continue
+		} else if incmsg.degree == m.degree && incmsg.rndval < m.rndval {
+			continue
+		}
+		return false
 	}
+	return true
+}
 
-	m := messageShared{
-		degree: degree,
-		rndval: -1,
-		avail:  set,
+// dlfSharedCSR is dlfShared on a CSR, with the same phases, rounds and messages.
+// Each node's palette is a bitmask of the colors its neighbors have taken, set atomically since two winners may share a neighbor
+func dlfSharedCSR(c *g.CSR, opts RunOptions) RunStats {
+	debug := opts.Debug
+	net := NewCSRNetwork(c, opts)
+	pool := net.Pool()
+	seed := runSeed(opts)
+
+	numNodes := c.NumNodes()
+	paletteSize := c.MaxDegree + 1
+	words := (paletteSize + 31) / 32
+	taken := make([]uint32, numNodes*words)
+	degree := make([]int, numNodes)
+	rndval := make([]float32, numNodes)
+	selected := make([]int, numNodes)
+	won := make([]bool, numNodes)
+	uncolored := make([]int, numNodes)
+	for v := range uncolored {
+		degree[v] = c.Degree(v)
+		uncolored[v] = v
 	}
 
-	lock.Lock()
-	data[n.Name] = m
-	lock.Unlock()
-
-	iter := 0
-
-	for {
+	iterations := 0
+	for len(uncolored) > 0 {
 		if debug%2 == 1 {
-			fmt.Println(n.Name, "round:", iter)
+			fmt.Println("round:", iterations, "uncolored:", len(uncolored))
 		}
-
-		temp := rand.Float32()
-
-		lock.Lock()
-		m = data[n.Name]
-		m.rndval = temp
-		data[n.Name] = m
-		lock.Unlock()
-
-		selectedColor := m.avail.Values()[0].(int)
-
-		myColor := true
-		deg := m.degree
-		rnd := m.rndval
-
-		checkpoint2.Add(1)
-		checkpoint1.Done()
-		checkpoint1.Wait()
-
-		for _, neighbor := range n.Neighbors {
-			incmsg := data[neighbor.Name]
-			if debug%2 == 1 {
-				fmt.Println(n.Name, incmsg)
+		pool.For(len(uncolored), func(i int) {
+			v := uncolored[i]
+			rndval[v] = nodeFloat32(seed, iterations, v)
+			mask := taken[v*words : (v+1)*words]
+			selected[v] = -1
+			bits := intBits(degree[v]) + 32
+			for color := 0; color < paletteSize; color++ {
+				if mask[color/32]&(1<<uint(color%32)) != 0 {
+					continue
+				}
+				if selected[v] == -1 {
+					selected[v] = color
+				}
+				bits += intBits(color)
 			}
-			if incmsg.degree == -1 {
-				continue
+			net.ChargeInd(v, c.Degree(v), bits)
+		})
+		pool.For(len(uncolored), func(i int) {
+			v := uncolored[i]
+			won[v] = true
+			for _, u := range c.Neighbors(v) {
+				if degree[u] == -1 || degree[u] < degree[v] || (degree[u] == degree[v] && rndval[u] < rndval[v]) {
+					continue
+				}
+				won[v] = false
+				break
 			}
-
-			if !myColor {
-				continue
+		})
+		pool.For(len(uncolored), func(i int) {
+			v := uncolored[i]
+			if !won[v] {
+				return
 			}
-
-			if incmsg.degree < deg {
-				continue
-			} else if incmsg.degree == deg && incmsg.rndval < rnd {
-				continue
+			color := selected[v]
+			c.Colors[v] = color
+			for _, u := range c.Neighbors(v) {
+				word := &taken[int(u)*words+color/32]
+				bit := uint32(1) << uint(color%32)
+				for {
+					old := atomic.LoadUint32(word)
+					if old&bit != 0 || atomic.CompareAndSwapUint32(word, old, old|bit) {
+						break
+					}
+				}
 			}
+			degree[v] = -1
+		})
 
-			myColor = false
-		}
-
-		checkpoint3.Add(1)
-		checkpoint2.Done()
-		checkpoint2.Wait()
-
-		if myColor {
-			n.Color = selectedColor
-
-			m.degree = -1
-			lock.Lock()
-			for _, neighbor := range n.Neighbors {
-				data[neighbor.Name].avail.Remove(selectedColor)
-				if debug%2 == 1 {
-					fmt.Println(neighbor.Name, data[neighbor.Name].avail.Values())
-				}
+		remaining := uncolored[:0]
+		for _, v := range uncolored {
+			if !won[v] {
+				remaining = append(remaining, v)
 			}
-			data[n.Name] = m
-			lock.Unlock()
-			break
 		}
-
-		checkpoint1.Add(1)
-		checkpoint3.Done()
-		checkpoint3.Wait()
-
-		iter++
+		uncolored = remaining
+		iterations++
 	}
+	net.AddRounds(iterations)
 
-	checkpoint3.Done()
-
-	return
-
+	return net.Stats()
 }
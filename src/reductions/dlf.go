@@ -4,164 +4,181 @@ import (
 	"fmt"
 	s "github.com/goombaio/orderedset"
 	g "github.com/thomaseb191/go-coloring/graphs"
-	"math/rand"
-	"sync"
-	"time"
 )
 
+/*
+	Distributed Largest-First over channels
+		- dlfMessagePassing: runs DLF with one channel per directed edge, stepping the nodes on a bounded Pool
+	Every iteration takes two LOCAL rounds. In the first, each uncolored node sends its degree, random value and
+	candidate color to its uncolored neighbors. In the second, each node tells them whether it won and took its color.
+	A node that takes its color has told every uncolored neighbor so in that same round, so it closes its outgoing
+	channels and stops stepping. Its neighbors stop listening to it as soon as they read that message, and close their
+	channels to it, so every channel is closed exactly once by its sender.
+*/
+
 type message struct {
 	degree   int
 	rndvalue float32
 	color    int
+	done     bool
 }
 
-var availableColors = make(map[string]*s.OrderedSet)
-
-func dlf(gr g.Graph, poolSize int, debug int) g.Graph {
-
-	var wg sync.WaitGroup
-	wg.Add(len(gr.Nodes))
-
-	incoming := make(map[string][]chan message)
-	outgoing := make(map[string][]chan message)
-
-	for _, node := range gr.Nodes {
-		for _, neighbor := range node.Neighbors {
-			edge := make(chan message, 1)
-			outgoing[node.Name] = append(outgoing[node.Name], edge)
-			incoming[neighbor.Name] = append(incoming[neighbor.Name], edge)
-		}
-	}
-
-	var wglist = make([]*sync.WaitGroup, len(gr.Nodes))
-
-	for i := 0; i < len(wglist); i++ {
-		var newwg sync.WaitGroup
-		wglist[i] = &newwg
-	}
+// Bits counts the degree, the 32-bit random value, the candidate color and the done flag
+func (m message) Bits() int {
+	return intBits(m.degree) + 32 + intBits(m.color) + 1
+}
 
-	if debug % 2 == 1 {
-		println(incoming)
-		println(outgoing)
+// beats returns whether a proposal from the node with index ind has priority over one from otherInd.
+// Higher degrees win, then higher random values, and equal random values fall back to the higher index
+func (m message) beats(ind int, other message, otherInd int) bool {
+	if m.degree != other.degree {
+		return m.degree > other.degree
 	}
-
-	wglist[0].Add(len(gr.Nodes))
-	var lock sync.Mutex
-	for _, node := range gr.Nodes {
-		if debug % 2 == 1 {
-			fmt.Println(node.Name, incoming[node.Name], outgoing[node.Name])
-		}
-		node := node
-		go func() {
-			vertex(node, incoming[node.Name], outgoing[node.Name], gr.MaxDegree, wglist, &lock, debug)
-			wg.Done()
-			for {
-				for _, ch := range incoming[node.Name] {
-					_, ok := <-ch
-					if !ok {
-						continue
-					}
-					//fmt.Println(info)
-				}
-			}
-		}()
+	if m.rndvalue != other.rndvalue {
+		return m.rndvalue > other.rndvalue
 	}
-
-	wg.Wait()
-
-	return gr
+	return ind > otherInd
 }
 
-func vertex(n *g.Node, incoming []chan message, outgoing []chan message, maxDegree int, wg []*sync.WaitGroup, lock *sync.Mutex, debug int) {
-	rand.Seed(time.Now().UnixNano())
-	degree := len(n.Neighbors)
-
-	set := s.NewOrderedSet()
-
-	for i := 0; i <= maxDegree; i++ {
-		set.Add(i)
-	}
-
-	lock.Lock()
-	availableColors[n.Name] = set
-	lock.Unlock()
-
-	m := message{
-		degree:   degree,
-		rndvalue: -1,
-		color:    -1,
-	}
+// dlfEdge is one end of an edge as seen by a node
+//		neighbor: the node on the other end
+//		in: the channel the neighbor sends on
+//		out: the channel the node sends on
+type dlfEdge struct {
+	neighbor *g.Node
+	in       chan message
+	out      chan message
+}
 
-	iter := 0
+// dlfVertex is the state a node of dlfMessagePassing keeps between phases
+//		node: the node itself
+//		palette: the colors no colored neighbor has taken yet. Only this node's own steps touch it
+//		edges: the edges to neighbors that are still uncolored
+//		proposal: the message the node sent in the current iteration
+//		won: whether the node took its color in the current iteration
+type dlfVertex struct {
+	node     *g.Node
+	palette  *s.OrderedSet
+	edges    []dlfEdge
+	proposal message
+	won      bool
+}
 
-	for {
-		if debug % 2 == 1 {
-			fmt.Println(n.Name, "round: ", iter, len(incoming))
+// dlfMessagePassing runs Distributed Largest-First with every node exchanging messages with its neighbors over channels.
+// The uncolored nodes are stepped on the Network's Pool, one phase at a time: every node sends its proposal,
+// then every node reads its neighbors' proposals and sends its result, then every node that lost reads its neighbors' results.
+// Each channel has room for two messages, as a node may send its result before its neighbor has read its proposal,
+// so no send ever blocks and a node only ever waits on messages sent in an earlier phase.
+// Every channel is closed by the time the run returns, and no goroutines outlive it.
+func dlfMessagePassing(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	debug := opts.Debug
+	net := NewNetwork(&gr, opts)
+	pool := net.Pool()
+	seed := runSeed(opts)
+
+	vertices := make([]*dlfVertex, len(gr.Nodes))
+	for _, node := range gr.Nodes {
+		palette := s.NewOrderedSet()
+		for i := 0; i <= gr.MaxDegree; i++ {
+			palette.Add(i)
 		}
-		m.rndvalue = rand.Float32()
-		m.color = set.Values()[0].(int)
-		//fmt.Println(outgoing)
-		for _, ch := range outgoing {
-			ch <- m
+		vertices[node.Ind] = &dlfVertex{
+			node:     node,
+			palette:  palette,
+			proposal: message{degree: len(node.Neighbors), rndvalue: -1, color: -1},
 		}
-
-		deg := m.degree
-		rnd := m.rndvalue
-
-		mycolor := true
-
-		var newincoming []chan message
-
-		for _, ch := range incoming {
-			incmsg := <-ch
-
-			if incmsg.color == -1 {
-				//incoming = append(incoming[:i], incoming[i:]...)
-				continue
-			}
-
-			newincoming = append(newincoming, ch)
-
-			if incmsg.degree < deg {
-				continue
-			} else if incmsg.degree == deg && incmsg.rndvalue < rnd {
+	}
+	for _, node := range gr.Nodes {
+		for _, neighbor := range node.Neighbors {
+			if node.Ind > neighbor.Ind {
 				continue
 			}
-
-			mycolor = false
-			deg = incmsg.degree
-			rnd = incmsg.rndvalue
+			toNeighbor := make(chan message, 2)
+			fromNeighbor := make(chan message, 2)
+			vertices[node.Ind].edges = append(vertices[node.Ind].edges, dlfEdge{neighbor: neighbor, in: fromNeighbor, out: toNeighbor})
+			vertices[neighbor.Ind].edges = append(vertices[neighbor.Ind].edges, dlfEdge{neighbor: node, in: toNeighbor, out: fromNeighbor})
 		}
+	}
 
-		incoming = newincoming
+	uncolored := vertices
+	iterations := 0
+	for len(uncolored) > 0 {
+		iterations++
+		if debug%2 == 1 {
+			fmt.Println("round:", iterations, "uncolored:", len(uncolored))
+		}
+		pool.For(len(uncolored), func(i int) {
+			uncolored[i].propose(net, seed, iterations)
+		})
+		pool.For(len(uncolored), func(i int) {
+			uncolored[i].decide(net)
+		})
+		pool.For(len(uncolored), func(i int) {
+			if !uncolored[i].won {
+				uncolored[i].listen()
+			}
+		})
 
-		if mycolor {
-			n.Color = m.color
-			//fmt.Println("Color", m.color, "selected by node ", n.Name, "in round", iter)
-			lock.Lock()
-			for _, neighbor := range n.Neighbors {
-				availableColors[neighbor.Name].Remove(n.Color)
+		remaining := make([]*dlfVertex, 0, len(uncolored))
+		for _, v := range uncolored {
+			if !v.won {
+				remaining = append(remaining, v)
 			}
-			lock.Unlock()
-			break
 		}
+		uncolored = remaining
+	}
+	net.AddRounds(2 * iterations)
 
-		wg[iter+1].Add(1)
-		wg[iter].Done()
-		wg[iter].Wait()
-
-		iter++
+	return gr, net.Stats()
+}
 
+// propose sends the node's degree, a fresh random value for the iteration and its smallest available color to every uncolored neighbor
+func (v *dlfVertex) propose(net *Network, seed int64, iteration int) {
+	v.proposal.rndvalue = nodeFloat32(seed, iteration, v.node.Ind)
+	v.proposal.color = v.palette.Values()[0].(int)
+	for _, e := range v.edges {
+		e.out <- v.proposal
 	}
+	net.Charge(v.node, len(v.edges), v.proposal.Bits())
+}
 
-	for _, ch := range outgoing {
-		ch <- message{color: -1}
-		close(ch)
+// decide reads every uncolored neighbor's proposal and tells them whether the node beat them all.
+// A node that won takes its color and closes its channels, since its neighbors never read from it again
+func (v *dlfVertex) decide(net *Network) {
+	v.won = true
+	for _, e := range v.edges {
+		incmsg := <-e.in
+		if !v.proposal.beats(v.node.Ind, incmsg, e.neighbor.Ind) {
+			v.won = false
+		}
 	}
 
-	//fmt.Println(n.Name, "done")
+	result := message{degree: v.proposal.degree, color: v.proposal.color, done: v.won}
+	for _, e := range v.edges {
+		e.out <- result
+	}
+	net.Charge(v.node, len(v.edges), result.Bits())
 
-	wg[iter].Done()
+	if v.won {
+		v.node.Color = v.proposal.color
+		for _, e := range v.edges {
+			close(e.out)
+		}
+		v.edges = nil
+	}
+}
 
-	return
+// listen reads every uncolored neighbor's result, dropping each neighbor that took a color along with that color
+func (v *dlfVertex) listen() {
+	remaining := v.edges[:0]
+	for _, e := range v.edges {
+		incmsg := <-e.in
+		if incmsg.done {
+			v.palette.Remove(incmsg.color)
+			close(e.out)
+			continue
+		}
+		remaining = append(remaining, e)
+	}
+	v.edges = remaining
 }
@@ -0,0 +1,127 @@
+package reductions
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+)
+
+/*
+	Randomized (Delta+1)-coloring by random color trials, after Johansson and Luby
+		- johanssonReduction: colors a graph with MaxDegree+1 colors in O(log n) rounds with high probability
+	Every iteration takes two LOCAL rounds. In the first, every uncolored node picks a color uniformly at random from the
+	colors its colored neighbors have not taken, and sends it to its uncolored neighbors. In the second, every node whose
+	pick no neighbor shares keeps it and announces it as final. Each node keeps its pick with probability at least 1/4,
+	so the number of uncolored nodes shrinks geometrically.
+	The initial coloring is ignored, so every node starts uncolored.
+*/
+
+// trialMessage is what a node tells its uncolored neighbors in a round of johanssonReduction
+//		Color: the color it picked or kept
+//		Final: whether the node kept Color, rather than just picking it
+type trialMessage struct {
+	Color int
+	Final bool
+}
+
+// Bits counts the color and the final flag
+func (m trialMessage) Bits() int {
+	return intBits(m.Color) + 1
+}
+
+// johanssonReduction colors a graph by random color trials.
+// Its random choices come from nodeRandom, keyed on the run's seed, the iteration and the node,
+// so a run with a given seed picks the same colors whatever the pool size or the order nodes step in
+func johanssonReduction(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	seed := runSeed(opts)
+	if opts.Debug%2 == 1 {
+		fmt.Printf("Starting Johansson Reduction with seed %d \n", seed)
+	}
+	net := NewNetwork(&gr, opts)
+
+	paletteSize := gr.MaxDegree + 1
+	taken := make([]bool, len(gr.Nodes)*paletteSize)
+	picked := make([]int, len(gr.Nodes))
+	// colored only changes between rounds, so it is every node's knowledge of which neighbors sent a final color
+	colored := make([]bool, len(gr.Nodes))
+	kept := make([]bool, len(gr.Nodes))
+	for _, node := range gr.Nodes {
+		node.Color = -1
+	}
+
+	uncolored := make([]*g.Node, len(gr.Nodes))
+	copy(uncolored, gr.Nodes)
+	for iter := 0; len(uncolored) > 0; iter++ {
+		if opts.Debug%2 == 1 {
+			fmt.Printf("\tIteration %d: %d uncolored nodes \n", iter, len(uncolored))
+		}
+
+		net.SetPhase(fmt.Sprintf("trial %d", iter))
+		net.RoundOn(uncolored, func(v *g.Node, inbox []Message, out *Outbox) {
+			palette := taken[v.Ind*paletteSize : (v.Ind+1)*paletteSize]
+			for _, msg := range inbox {
+				if m, ok := msg.Payload.(trialMessage); ok && m.Final {
+					palette[m.Color] = true
+				}
+			}
+			picked[v.Ind] = pickFreeColor(palette, nodeRandom(seed, iter, v.Ind))
+			sendToUncolored(v, out, colored, trialMessage{Color: picked[v.Ind]})
+		})
+
+		net.SetPhase(fmt.Sprintf("keep %d", iter))
+		net.RoundOn(uncolored, func(v *g.Node, inbox []Message, out *Outbox) {
+			kept[v.Ind] = true
+			for _, msg := range inbox {
+				if m, ok := msg.Payload.(trialMessage); ok && !m.Final && m.Color == picked[v.Ind] {
+					kept[v.Ind] = false
+				}
+			}
+			if kept[v.Ind] {
+				v.Color = picked[v.Ind]
+				sendToUncolored(v, out, colored, trialMessage{Color: v.Color, Final: true})
+			}
+		})
+
+		remaining := uncolored[:0]
+		for _, node := range uncolored {
+			if kept[node.Ind] {
+				colored[node.Ind] = true
+			} else {
+				remaining = append(remaining, node)
+			}
+		}
+		uncolored = remaining
+	}
+
+	return gr, net.Stats()
+}
+
+// sendToUncolored sends a message to every neighbor of v that has not announced a final color
+func sendToUncolored(v *g.Node, out *Outbox, colored []bool, msg trialMessage) {
+	for _, neighbor := range v.Neighbors {
+		if !colored[neighbor.Ind] {
+			out.Send(neighbor, msg)
+		}
+	}
+}
+
+// pickFreeColor returns the free color of a palette at position r modulo the number of free colors.
+// palette[c] is true when color c is taken. At most MaxDegree of the MaxDegree+1 colors can be taken, so one is always free
+func pickFreeColor(palette []bool, r uint64) int {
+	numFree := 0
+	for _, isTaken := range palette {
+		if !isTaken {
+			numFree++
+		}
+	}
+	k := int(r % uint64(numFree))
+	for color, isTaken := range palette {
+		if isTaken {
+			continue
+		}
+		if k == 0 {
+			return color
+		}
+		k--
+	}
+	return -1
+}
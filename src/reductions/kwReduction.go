@@ -4,124 +4,157 @@ import (
 	"fmt"
 	g "github.com/thomaseb191/go-coloring/graphs"
 )
-// runNaiveGoRoutine is a helper function that runs the naive algorithm as a goroutine and
-// sends the result back through a channel.
-func runNaiveGoRoutine(gr g.Graph, poolSize int, debug int, c chan g.Graph) {
-	c <- RunNaive(gr, poolSize, debug)
-}
 
-// convertBinsToGraph is a helper method that converts color "bins" into graphs.
-func convertBinsToGraph(bins [][]*g.Node, original *g.Graph) *g.Graph {
-	for color := 0; color < len(bins); color++ {
-		for _, node := range bins[color] {
-			node.Color = color
-		}
-	}
-	return original
+// KWPhase traces a single merge phase of the Kuhn-Wattenhofer reduction
+//		BinsIn: the number of color bins the phase started with
+//		BinsOut: the number of color bins left once every group was merged
+//		Groups: the number of groups of up to 2*(MaxDegree+1) bins the phase merged in parallel
+//		MaxInnerSteps: the most color classes any group recolored, one per round, which is the rounds the phase took
+type KWPhase struct {
+	BinsIn int
+	BinsOut int
+	Groups int
+	MaxInnerSteps int
 }
 
-func checkIfNodeInColorSet(colorSet []*g.Node, neighbors []*g.Node) bool {
-	hasAny := false
-	for _, k := range neighbors {
-		for _, node := range colorSet {
-			if k.Name == node.Name {
-				hasAny = true
-				break
-			}
+// KWRoundBound returns the O(MaxDegree log(k/MaxDegree)) bound on the merge rounds of KW from k colors:
+// every phase at least halves the number of bins above MaxDegree+1 and takes at most MaxDegree+1 rounds
+func KWRoundBound(numColors int, maxDegree int) int {
+	phases := 0
+	for bins := numColors; bins > maxDegree+1; phases++ {
+		groupSize := 2 * (maxDegree + 1)
+		full, rest := bins/groupSize, bins%groupSize
+		bins = full * (maxDegree + 1)
+		if rest > maxDegree+1 {
+			rest = maxDegree + 1
 		}
+		bins += rest
 	}
-	return hasAny
+	return phases * (maxDegree + 1)
 }
 
-func combineColorsWithoutNaive(bins [][]*g.Node, gr g.Graph, c chan [][]*g.Node) {
-	maxDegree := gr.MaxDegree
-	//fmt.Printf("Number of colors in bins: %d\n", len(bins))
-	for k := maxDegree + 1; k < len(bins); k++ {
-		for j := 0; j < len(bins[k]); j++ {
-			for color := 0; color < maxDegree + 1; color++ {
-				hasAny := checkIfNodeInColorSet(bins[color], bins[k][j].Neighbors)
-				if ! hasAny {
-					bins[color] = append(bins[color], bins[k][j])
-					break
-				}
-			}
-		}
-	}
-	if len(bins) < maxDegree + 1 {
-		c <- bins
-	} else {
-		c <- bins[:maxDegree + 1]
-	}
+// traceKWPhase appends a merge phase to the KWPhases of the Network's Stats
+func (n *Network) traceKWPhase(phase KWPhase) {
+	n.kwPhases = append(n.kwPhases, phase)
 }
 
 // kwReduction is the main method that runs the KW algorithm.
-func kwReduction(gr g.Graph, poolSize int, debug int) g.Graph {
-	if debug % 2 == 1 {
+func kwReduction(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	net := NewNetwork(&gr, opts)
+	outGraph := kwOnNetwork(gr, net, opts, false)
+	return outGraph, net.Stats()
+}
+
+// kwOnNetwork runs the KW algorithm, recording its rounds and messages on net.
+// colorsKnown should be true if every node has already announced its current color over net.
+// Each merge phase splits the bins into groups of 2*(MaxDegree+1) and recolors the classes past the first MaxDegree+1 of every group
+// one slot per round, with the nodes of that slot in every group stepping together on the Network's Pool,
+// so a phase costs as many rounds as the largest group has classes past the first MaxDegree+1. Every phase is traced in RunStats.KWPhases
+func kwOnNetwork(gr g.Graph, net *Network, opts RunOptions, colorsKnown bool) g.Graph {
+	if opts.Debug % 2 == 1 {
 		fmt.Printf("Starting KW Reduction \n")
 	}
 	degree := gr.MaxDegree
-	startIndexes := make([]int, 0)
 	size := len(gr.Nodes)
-	c := make(chan g.Graph)
 	// If we can't split the graph into bins,
 	if size < 2 * (degree + 1) {
 		gr.Description = "Color Reduced with KW"
-		go runNaiveGoRoutine(gr, poolSize, debug, c)
-		return <- c
+		return naiveOnNetwork(gr, net, opts, colorsKnown)
 	}
-	for x := 0; x < size; x++ {
-		if x % (2 * (degree + 1)) == 0 {
-			startIndexes = append(startIndexes, x)
+	if !colorsKnown {
+		for _, node := range gr.Nodes {
+			net.Charge(node, len(node.Neighbors), intBits(node.Color))
+		}
+		net.AddRounds(1)
+		if net.Recording() {
+			net.RecordColors("announce", 0, g.ColorsOf(&gr), nil)
 		}
 	}
-
-	numColors := g.CountColors(&gr)
-	colorBins := make([][]*g.Node, numColors)
+	// Every node's color is replaced by the index of its bin, numbered in order of first appearance
+	bin := make([]int, size)
 	colorToIndex := make(map[int]int)
-	latestIndex := 0
 	for _, node := range gr.Nodes {
 		if _, ok := colorToIndex[node.Color]; ! ok {
-			colorToIndex[node.Color] = latestIndex
-			latestIndex++
+			colorToIndex[node.Color] = len(colorToIndex)
 		}
-		colorBins[colorToIndex[node.Color]] = append(colorBins[colorToIndex[node.Color]], node)
+		bin[node.Ind] = colorToIndex[node.Color]
 	}
+	numBins := len(colorToIndex)
 
-	for len(colorBins) > degree + 1 {
-		//fmt.Printf("Number of bins: %d\n", len(colorBins))
-		d := make(chan [][]*g.Node)
-		binIndexes := make([]int, 0)
-		colors := len(colorBins)
+	pool := net.Pool()
+	next := make([]int, size)
+	for numBins > degree + 1 {
+		//fmt.Printf("Number of bins: %d\n", numBins)
+		groupSize := 2 * (degree + 1)
+		numGroups := (numBins + groupSize - 1) / groupSize
+		colorBits := intBits(numBins)
 
-		for x := 0; x < colors; x++ {
-			if x%(2*(degree+1)) == 0 {
-				binIndexes = append(binIndexes, x)
+		// classes[s] holds every node in the bin at slot degree+1+s of its group, across all groups, in index order
+		classes := make([][]*g.Node, groupSize - (degree + 1))
+		for _, node := range gr.Nodes {
+			if slot := bin[node.Ind] % groupSize; slot > degree {
+				classes[slot-(degree+1)] = append(classes[slot-(degree+1)], node)
 			}
 		}
-		tempBins := make([][]*g.Node, 0)
 
-		for i := 0; i < len(binIndexes); i++ {
-			currStart := binIndexes[i]
-			var nextStart int
-			if i+1 != len(binIndexes) {
-				nextStart = binIndexes[i+1]
-			} else {
-				nextStart = len(colorBins)
+		steps := 0
+		for _, class := range classes {
+			if len(class) == 0 {
+				break
 			}
-			go combineColorsWithoutNaive(colorBins[currStart:nextStart], gr, d)
-		}
-		for i := 0; i < len(binIndexes); i++ {
-			bins := <-d
-			tempBins = append(tempBins, bins...)
+			// Every group's class at this slot is an independent set, and so is their union, since an edge between groups
+			// cannot clash once the groups get disjoint colors. Each node picks the first slot of its group that no neighbor
+			// in the group holds, then every pick is applied at once
+			pool.For(len(class), func(i int) {
+				v := class[i]
+				next[v.Ind] = freeSlot(v, bin, groupSize, degree)
+			})
+			pool.For(len(class), func(i int) {
+				v := class[i]
+				bin[v.Ind] = bin[v.Ind] - bin[v.Ind]%groupSize + next[v.Ind]
+				net.Charge(v, len(v.Neighbors), colorBits)
+			})
+			net.AddRounds(1)
+			steps++
 		}
 
-		close(d)
+		// Group i keeps its first degree+1 slots, which become bins i*(degree+1) onward
+		pool.For(size, func(v int) {
+			bin[v] = bin[v]/groupSize*(degree+1) + bin[v]%groupSize
+		})
+		binsOut := (numGroups - 1) * (degree + 1)
+		if last := numBins - (numGroups-1)*groupSize; last < degree+1 {
+			binsOut += last
+		} else {
+			binsOut += degree + 1
+		}
 
-		colorBins = tempBins
-		tempBins = make([][]*g.Node, 0)
+		net.traceKWPhase(KWPhase{BinsIn: numBins, BinsOut: binsOut, Groups: numGroups, MaxInnerSteps: steps})
+		if net.Recording() {
+			net.RecordColors(fmt.Sprintf("kw merge to %d colors", binsOut), 0, append([]int(nil), bin...), nil)
+		}
+		numBins = binsOut
+	}
+	for _, node := range gr.Nodes {
+		node.Color = bin[node.Ind]
 	}
-	graph := convertBinsToGraph(colorBins, &gr)
-	return *graph
+	return gr
 }
 
-
+// freeSlot returns the first slot in [0, degree] of v's group that no neighbor in the same group holds.
+// Since v has at most degree neighbors, one is always free
+func freeSlot(v *g.Node, bin []int, groupSize int, degree int) int {
+	group := bin[v.Ind] / groupSize
+	used := make([]bool, degree+1)
+	for _, neighbor := range v.Neighbors {
+		if b := bin[neighbor.Ind]; b/groupSize == group && b%groupSize <= degree {
+			used[b%groupSize] = true
+		}
+	}
+	for slot, taken := range used {
+		if !taken {
+			return slot
+		}
+	}
+	return -1
+}
@@ -0,0 +1,177 @@
+package reductions
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"math"
+)
+
+// linialParams holds the finite field and polynomial degree for a single Linial iteration
+//		Q: the prime order of the field GF(Q). New colors fall within [0, Q*Q)
+//		D: the maximum degree of the polynomials assigned to each old color
+type linialParams struct {
+	Q int
+	D int
+}
+
+// linialReduction is Linial's algorithm followed by a Kuhn-Wattenhofer reduction of its result
+// Linial's algorithm is comprised of the following steps, repeated until the number of colors stops shrinking
+//		Choose a prime Q and degree D such that Q > MaxDegree*D and every current color fits in D+1 base-Q digits
+//		Map every color c to the polynomial p_c over GF(Q) whose coefficients are the base-Q digits of c
+//		[Parallel] Every node picks the first x in GF(Q) with p_v(x) != p_u(x) for all neighbors u, and takes color x*Q + p_v(x)
+// Two distinct polynomials of degree D agree on at most D points, so a node's MaxDegree neighbors rule out at most MaxDegree*D < Q values of x.
+// The result is an O(Delta^2 log^2 n) coloring after log*(n) + O(1) iterations which kwReduction then reduces to MaxDegree+1 colors.
+// Based on https://www.cs.bgu.ac.il/~elkinm/book.pdf (Chapter 3.10)
+func linialReduction(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	if opts.Debug%2 == 1 {
+		fmt.Printf("Starting Linial Reduction \n")
+	}
+	net := NewNetwork(&gr, opts)
+	known := make([]map[int]int, len(gr.Nodes))
+
+	// Every node starts by announcing its color to its neighbors
+	net.SetPhase("announce")
+	net.Round(func(v *g.Node, inbox []Message, out *Outbox) {
+		known[v.Ind] = make(map[int]int)
+		out.Broadcast(v.Color)
+	})
+
+	numColors := maxColor(&gr) + 1
+	for iter := 0; ; iter++ {
+		params := chooseLinialParams(numColors, gr.MaxDegree)
+		if params.Q*params.Q >= numColors {
+			break
+		}
+		if opts.Debug%2 == 1 {
+			fmt.Printf("\tIteration %d: %d colors, Q = %d, D = %d \n", iter, numColors, params.Q, params.D)
+		}
+		net.SetPhase(fmt.Sprintf("linial iteration %d", iter))
+		net.Round(func(v *g.Node, inbox []Message, out *Outbox) {
+			colorsFromInbox(known[v.Ind], inbox)
+			v.Color = linialColor(v.Color, known[v.Ind], params)
+			out.Broadcast(v.Color)
+		})
+		numColors = params.Q * params.Q
+	}
+
+	if opts.Debug%2 == 1 {
+		fmt.Printf("\tStarting KW on %d colors \n", g.CountColors(&gr))
+	}
+	// The last broadcast already told every node its neighbors' colors, so KW can skip its own announcement
+	return kwOnNetwork(gr, net, opts, true), net.Stats()
+}
+
+// linialColor computes the new color of a node from its own color and the colors its neighbors announced
+func linialColor(color int, neighborColors map[int]int, params linialParams) int {
+	mine := polynomialCoefficients(color, params)
+	neighbors := make([][]int, 0, len(neighborColors))
+	for _, neighborColor := range neighborColors {
+		neighbors = append(neighbors, polynomialCoefficients(neighborColor, params))
+	}
+
+	for x := 0; x < params.Q; x++ {
+		val := evalPolynomial(mine, x, params.Q)
+		free := true
+		for _, coeffs := range neighbors {
+			if evalPolynomial(coeffs, x, params.Q) == val {
+				free = false
+				break
+			}
+		}
+		if free {
+			return x*params.Q + val
+		}
+	}
+	// Unreachable for a proper coloring since Q > MaxDegree*D
+	return color
+}
+
+// chooseLinialParams finds the (Q, D) pair with the smallest prime Q such that Q > maxDegree*D and Q^(D+1) >= numColors
+func chooseLinialParams(numColors int, maxDegree int) linialParams {
+	best := linialParams{Q: math.MaxInt32, D: 1}
+	for d := 1; d < 64; d++ {
+		lower := maxDegree*d + 1
+		root := int(math.Ceil(math.Pow(float64(numColors), 1/float64(d+1))))
+		if root > lower {
+			lower = root
+		}
+		q := nextPrime(lower)
+		for capPow(q, d+1) < numColors {
+			q = nextPrime(q + 1)
+		}
+		if q < best.Q {
+			best = linialParams{Q: q, D: d}
+		}
+		if maxDegree*d+1 > best.Q {
+			break
+		}
+	}
+	return best
+}
+
+// polynomialCoefficients returns the D+1 base-Q digits of a color, least significant first
+func polynomialCoefficients(color int, params linialParams) []int {
+	coeffs := make([]int, params.D+1)
+	for i := range coeffs {
+		coeffs[i] = color % params.Q
+		color /= params.Q
+	}
+	return coeffs
+}
+
+// evalPolynomial evaluates a polynomial with the given coefficients at x over GF(q) using Horner's method
+func evalPolynomial(coeffs []int, x int, q int) int {
+	res := 0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		res = (res*x + coeffs[i]) % q
+	}
+	return res
+}
+
+// capPow returns base^exp, saturating at math.MaxInt64 instead of overflowing
+func capPow(base int, exp int) int {
+	res := 1
+	for i := 0; i < exp; i++ {
+		if res > math.MaxInt64/base {
+			return math.MaxInt64
+		}
+		res *= base
+	}
+	return res
+}
+
+// nextPrime returns the smallest prime >= n
+func nextPrime(n int) int {
+	if n <= 2 {
+		return 2
+	}
+	for ; ; n++ {
+		if isPrime(n) {
+			return n
+		}
+	}
+}
+
+// isPrime checks primality by trial division, which is plenty for field sizes of O(Delta log n)
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// maxColor returns the largest color currently assigned in the Graph
+func maxColor(gr *g.Graph) int {
+	res := 0
+	for _, node := range gr.Nodes {
+		if node.Color > res {
+			res = node.Color
+		}
+	}
+	return res
+}
@@ -0,0 +1,281 @@
+package reductions
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"sync/atomic"
+)
+
+/*
+	Synchronous LOCAL-model simulation
+		- Network: a message-passing engine over a Graph that runs per-node step functions in synchronous rounds
+		- RunStats: the round and message accounting of a single run
+		- RunOptions: the settings every algorithm is run with
+	See congest.go for how message sizes are measured in CONGEST mode, and recorder.go for per-round snapshots
+*/
+
+// RunOptions holds the settings for a single run of a Reducer
+//		PoolSize: the most worker goroutines a parallel algorithm may run at once (<= 0 for the default of sqrt(n)), see pool.go
+//		Debug: 0 if just generate output, 1 if allow prints, 2 if just graph and output, 3 if allow graph and prints
+//		Congest: the constant c of the CONGEST model's c*log2(n) bits per edge per round, or 0 for the LOCAL model
+//		Recorder: collects a snapshot of the coloring after every round for replay, or nil to record nothing
+//		Seed: the seed every randomized algorithm draws from, or 0 to seed from the clock, see random.go
+//		Topology: the topology CVThreeColoring is told its input has, path, cycle or forest, or "" to detect it
+type RunOptions struct {
+	PoolSize int
+	Debug    int
+	Congest  int
+	Recorder *Recorder
+	Seed     int64
+	Topology string
+}
+
+// RunStats holds the LOCAL-model cost of a single run, along with its CONGEST-model bandwidth
+//		Rounds: the number of synchronous communication rounds
+//		Messages: the total number of messages sent over all edges
+//		MaxNodeMessages: the largest number of messages sent by any single node
+//		Bits: the total number of bits sent over all edges
+//		MaxEdgeBits: the most bits sent over a single edge in a single round
+//		BitCap: the CONGEST per-edge, per-round bit budget, or 0 in the LOCAL model
+//		OversizedMessages: the number of messages that exceeded BitCap
+//		CongestRounds: the rounds needed once every oversized message is split across several rounds
+//		Workers: the number of worker goroutines the run's Pool allowed
+//		KWPhases: every merge phase of a Kuhn-Wattenhofer reduction in the run, in order, or nil if it had none
+//		CVIterations: the number of Cole-Vishkin iterations the run took to reach 6 colors, or 0 if it ran none
+//		Fallback: the ShortID of the algorithm that ran instead, when the one asked for cannot color the graph, or "" if it ran itself
+type RunStats struct {
+	Rounds            int
+	Messages          int
+	MaxNodeMessages   int
+	Bits              int
+	MaxEdgeBits       int
+	BitCap            int
+	OversizedMessages int
+	CongestRounds     int
+	Workers           int
+	KWPhases          []KWPhase
+	CVIterations      int
+	Fallback          string
+}
+
+// CongestCompliant returns whether every message fit within BitCap, which always holds in the LOCAL model
+func (s RunStats) CongestCompliant() bool {
+	return s.OversizedMessages == 0
+}
+
+// Message is a single message sent over an edge during a round
+//		From: the Ind of the sending Node
+//		To: the Ind of the receiving Node
+//		Payload: the algorithm-specific contents of the message
+//		Bits: the size of Payload on the wire
+type Message struct {
+	From    int
+	To      int
+	Payload interface{}
+	Bits    int
+}
+
+// StepFunc is run once per active node per round.
+// inbox holds every message sent to v since v last stepped, and out collects the messages v sends this round.
+// A StepFunc may only touch the state of v itself, so that every node of a round can step in parallel.
+type StepFunc func(v *g.Node, inbox []Message, out *Outbox)
+
+// Outbox collects the messages a single node sends during a round
+type Outbox struct {
+	from *g.Node
+	msgs []Message
+}
+
+// Send queues a message to a neighbor, to be delivered at the end of the round.
+// Sending to a node that is not a neighbor is a bug in the algorithm and panics.
+func (o *Outbox) Send(to *g.Node, payload interface{}) {
+	if !isNeighbor(o.from, to) {
+		panic(fmt.Sprintf("reductions: node %s sent a message to non-neighbor %s", o.from.Name, to.Name))
+	}
+	o.msgs = append(o.msgs, Message{From: o.from.Ind, To: to.Ind, Payload: payload, Bits: payloadBits(payload)})
+}
+
+// Broadcast queues the same message to every neighbor
+func (o *Outbox) Broadcast(payload interface{}) {
+	size := payloadBits(payload)
+	for _, neighbor := range o.from.Neighbors {
+		o.msgs = append(o.msgs, Message{From: o.from.Ind, To: neighbor.Ind, Payload: payload, Bits: size})
+	}
+}
+
+// Network simulates the synchronous LOCAL model over a Graph.
+// Messages sent during a round are only delivered once every active node has finished stepping,
+// so no node can observe anything sent in the round it is currently running.
+// In CONGEST mode every message is measured against the bit cap, and a round in which some edge carries
+// more than the cap is counted as the number of rounds needed to split that edge's payload.
+type Network struct {
+	gr            *g.Graph
+	csr           *g.CSR
+	pool          *Pool
+	inboxes       [][]Message
+	sent          []int64
+	rounds        int64
+	bitCap        int
+	bits          int64
+	maxEdgeBits   int64
+	oversized     int64
+	congestRounds int64
+	pendingSplit  int64
+	recorder      *Recorder
+	phase         string
+	kwPhases      []KWPhase
+}
+
+// NewNetwork builds a Network over a Graph whose Nodes' Ind match their position in gr.Nodes
+func NewNetwork(gr *g.Graph, opts RunOptions) *Network {
+	return &Network{
+		gr:         gr,
+		pool:       NewPool(len(gr.Nodes), opts.PoolSize),
+		inboxes:    make([][]Message, len(gr.Nodes)),
+		sent:       make([]int64, len(gr.Nodes)),
+		bitCap:     congestBitCap(len(gr.Nodes), opts.Congest),
+		recorder:   opts.Recorder,
+	}
+}
+
+// NewCSRNetwork builds a Network over a CSR, for algorithms that run on a CSR directly.
+// Such a Network has no Nodes to step, so its algorithms charge their messages with ChargeInd and their rounds with AddRounds
+func NewCSRNetwork(c *g.CSR, opts RunOptions) *Network {
+	return &Network{
+		csr:      c,
+		pool:     NewPool(c.NumNodes(), opts.PoolSize),
+		sent:     make([]int64, c.NumNodes()),
+		bitCap:   congestBitCap(c.NumNodes(), opts.Congest),
+		recorder: opts.Recorder,
+	}
+}
+
+// Pool returns the worker pool the Network steps nodes on, for algorithms that charge their rounds instead of running Round
+func (n *Network) Pool() *Pool {
+	return n.pool
+}
+
+// Round runs a synchronous round in which every node steps
+func (n *Network) Round(step StepFunc) {
+	n.RoundOn(n.gr.Nodes, step)
+}
+
+// RoundOn runs a synchronous round in which only the given nodes step.
+// Nodes that do not step keep their undelivered messages until the next round they step in.
+func (n *Network) RoundOn(active []*g.Node, step StepFunc) {
+	outboxes := make([]Outbox, len(active))
+	n.pool.For(len(active), func(i int) {
+		v := active[i]
+		inbox := n.inboxes[v.Ind]
+		n.inboxes[v.Ind] = nil
+		outboxes[i].from = v
+		step(v, inbox, &outboxes[i])
+	})
+
+	// Barrier: deliver only after every node has stepped
+	roundSplit := 1
+	for _, out := range outboxes {
+		edgeBits := make(map[int]int)
+		for _, msg := range out.msgs {
+			n.inboxes[msg.To] = append(n.inboxes[msg.To], msg)
+			edgeBits[msg.To] += msg.Bits
+		}
+		for _, size := range edgeBits {
+			n.recordEdge(size, 1)
+			if split := splitRounds(size, n.bitCap); split > roundSplit {
+				roundSplit = split
+			}
+		}
+		atomic.AddInt64(&n.sent[out.from.Ind], int64(len(out.msgs)))
+	}
+	atomic.AddInt64(&n.rounds, 1)
+	atomic.AddInt64(&n.congestRounds, int64(roundSplit))
+	n.recordRound()
+}
+
+// Charge records messages sent by a node in algorithms that run their own synchronization instead of Round.
+// Each message is assumed to go over a different edge and to be msgBits long.
+// It is safe to call from several goroutines at once.
+func (n *Network) Charge(v *g.Node, numMessages int, msgBits int) {
+	n.ChargeInd(v.Ind, numMessages, msgBits)
+}
+
+// ChargeInd is Charge for the node at position v, for algorithms running on a CSR
+func (n *Network) ChargeInd(v int, numMessages int, msgBits int) {
+	atomic.AddInt64(&n.sent[v], int64(numMessages))
+	if numMessages == 0 {
+		return
+	}
+	n.recordEdge(msgBits, numMessages)
+	split := int64(splitRounds(msgBits, n.bitCap) - 1)
+	for {
+		pending := atomic.LoadInt64(&n.pendingSplit)
+		if split <= pending || atomic.CompareAndSwapInt64(&n.pendingSplit, pending, split) {
+			break
+		}
+	}
+}
+
+// AddRounds records rounds run by algorithms that run their own synchronization instead of Round.
+// In CONGEST mode, each of these rounds is stretched by the largest split needed by any message charged since the last call,
+// which is an upper bound on the true cost.
+func (n *Network) AddRounds(numRounds int) {
+	atomic.AddInt64(&n.rounds, int64(numRounds))
+	split := atomic.SwapInt64(&n.pendingSplit, 0)
+	atomic.AddInt64(&n.congestRounds, int64(numRounds)*(1+split))
+}
+
+// recordEdge records count edges each carrying edgeBits bits within a single round
+func (n *Network) recordEdge(edgeBits int, count int) {
+	atomic.AddInt64(&n.bits, int64(edgeBits*count))
+	if n.bitCap > 0 && edgeBits > n.bitCap {
+		atomic.AddInt64(&n.oversized, int64(count))
+	}
+	for {
+		max := atomic.LoadInt64(&n.maxEdgeBits)
+		if int64(edgeBits) <= max || atomic.CompareAndSwapInt64(&n.maxEdgeBits, max, int64(edgeBits)) {
+			break
+		}
+	}
+}
+
+// Stats returns the rounds, messages and bits recorded so far
+func (n *Network) Stats() RunStats {
+	stats := RunStats{
+		Rounds:            int(atomic.LoadInt64(&n.rounds)),
+		Bits:              int(atomic.LoadInt64(&n.bits)),
+		MaxEdgeBits:       int(atomic.LoadInt64(&n.maxEdgeBits)),
+		BitCap:            n.bitCap,
+		OversizedMessages: int(atomic.LoadInt64(&n.oversized)),
+		CongestRounds:     int(atomic.LoadInt64(&n.congestRounds)),
+		Workers:           n.pool.Size(),
+		KWPhases:          n.kwPhases,
+	}
+	for i := range n.sent {
+		sent := int(atomic.LoadInt64(&n.sent[i]))
+		stats.Messages += sent
+		if sent > stats.MaxNodeMessages {
+			stats.MaxNodeMessages = sent
+		}
+	}
+	return stats
+}
+
+// colorsFromInbox reads the latest color each neighbor announced, for algorithms whose payloads are plain int colors
+func colorsFromInbox(known map[int]int, inbox []Message) {
+	for _, msg := range inbox {
+		if color, ok := msg.Payload.(int); ok {
+			known[msg.From] = color
+		}
+	}
+}
+
+// isNeighbor checks whether to is in from's neighbor list
+func isNeighbor(from *g.Node, to *g.Node) bool {
+	for _, neighbor := range from.Neighbors {
+		if neighbor == to {
+			return true
+		}
+	}
+	return false
+}
@@ -9,24 +9,108 @@ import (
 /* Implements Naive Color Reduction Alg found here:
 https://stanford.edu/~rezab/classes/cme323/S16/projects_reports/bae.pdf
 MaxDegree 4 means there are 5 colors nodes can be colored as [0,1,2,3,4]
+Runs on the LOCAL-model Network, recoloring one node per round
+Every node whose color is outside [0, MaxDegree] is recolored, in node order, so any proper initial coloring works,
+not only the index coloring whose first MaxDegree+1 nodes already have valid colors.
+This is the sequential greedy pass. See naiveClasses.go for the distributed form that recolors a whole color class per round
  */
-func RunNaive(gr g.Graph, poolSize int, debug int) g.Graph {
-	if debug % 2 == 1 {
+func RunNaive(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	net := NewNetwork(&gr, opts)
+	outGraph := naiveOnNetwork(gr, net, opts, false)
+	return outGraph, net.Stats()
+}
+
+// naiveOnNetwork runs the naive reduction on an existing Network.
+// colorsKnown should be true if every node has already announced its current color over net
+func naiveOnNetwork(gr g.Graph, net *Network, opts RunOptions, colorsKnown bool) g.Graph {
+	if opts.Debug % 2 == 1 {
 		fmt.Printf("Starting reduction for %s algorithm...\n", "Naive")
 	}
-	go func() {}()
+	known := make([]map[int]int, len(gr.Nodes))
+	for i := range known {
+		known[i] = make(map[int]int)
+	}
+	if !colorsKnown {
+		net.SetPhase("announce")
+		net.Round(func(v *g.Node, inbox []Message, out *Outbox) {
+			out.Broadcast(v.Color)
+		})
+	}
+
+	net.SetPhase("naive")
+	for i, node := range gr.Nodes {
+		if node.Color >= 0 && node.Color <= gr.MaxDegree {
+			continue
+		}
+		net.RoundOn(gr.Nodes[i:i+1], func(v *g.Node, inbox []Message, out *Outbox) {
+			colorsFromInbox(known[v.Ind], inbox)
+			color := minFreeColor(known[v.Ind], gr.MaxDegree)
+			if color == -1 {
+				fmt.Printf("MinColor() did not return a valid value\n")
+			}
+			v.Color = color
+			out.Broadcast(color)
+		})
+	}
+
+	return gr
+}
+
+// runNaiveCSR is naiveOnNetwork on a CSR, with the same rounds, messages and result.
+// Since every recolored node announces its new color before the next round, the node stepping in a round
+// knows the current color of every neighbor, which it reads straight from c.Colors
+func runNaiveCSR(c *g.CSR, opts RunOptions) RunStats {
+	if opts.Debug % 2 == 1 {
+		fmt.Printf("Starting reduction for %s algorithm on a CSR...\n", "Naive")
+	}
+	net := NewCSRNetwork(c, opts)
 
-	size := len(gr.Nodes)
+	net.SetPhase("announce")
+	for v := 0; v < c.NumNodes(); v++ {
+		net.ChargeInd(v, c.Degree(v), intBits(c.Colors[v]))
+	}
+	net.AddRounds(1)
+	net.recordRound()
 
-	for i := gr.MaxDegree+1; i < size; i++ {
-		color := MinColor(*gr.Nodes[i], gr.MaxDegree)
+	net.SetPhase("naive")
+	used := make([]bool, c.MaxDegree+1)
+	for v := 0; v < c.NumNodes(); v++ {
+		if c.Colors[v] >= 0 && c.Colors[v] <= c.MaxDegree {
+			continue
+		}
+		color := minFreeColorCSR(c, v, used)
 		if color == -1 {
-			fmt.Errorf("MinColor() did not return a valid value")
+			fmt.Printf("MinColor() did not return a valid value\n")
 		}
-		gr.Nodes[i].Color = color
+		c.Colors[v] = color
+		net.ChargeInd(v, c.Degree(v), intBits(color))
+		net.AddRounds(1)
+		net.recordRound()
 	}
+	return net.Stats()
+}
 
-	return gr
+// minFreeColorCSR is minFreeColor for node v of a CSR. used must have MaxDegree+1 entries, and is left cleared for reuse
+func minFreeColorCSR(c *g.CSR, v int, used []bool) int {
+	neighbors := c.Neighbors(v)
+	for _, u := range neighbors {
+		if color := c.Colors[u]; color >= 0 && color < len(used) {
+			used[color] = true
+		}
+	}
+	free := -1
+	for i, taken := range used {
+		if !taken {
+			free = i
+			break
+		}
+	}
+	for _, u := range neighbors {
+		if color := c.Colors[u]; color >= 0 && color < len(used) {
+			used[color] = false
+		}
+	}
+	return free
 }
 
 func MinColor(n g.Node, maxDegree int) int {
@@ -46,3 +130,19 @@ func MinColor(n g.Node, maxDegree int) int {
 
 	return -1
 }
+
+// minFreeColor is MinColor for a node that only knows its neighbors' colors through the messages they sent
+func minFreeColor(neighborColors map[int]int, maxDegree int) int {
+	used := make([]bool, maxDegree+1)
+	for _, color := range neighborColors {
+		if color >= 0 && color <= maxDegree {
+			used[color] = true
+		}
+	}
+	for i, taken := range used {
+		if !taken {
+			return i
+		}
+	}
+	return -1
+}
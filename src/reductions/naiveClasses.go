@@ -0,0 +1,67 @@
+package reductions
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"sort"
+)
+
+// RunNaiveClasses
+/* Implements the distributed form of the Naive Color Reduction Alg found here:
+https://stanford.edu/~rezab/classes/cme323/S16/projects_reports/bae.pdf
+Each round, every node of the highest remaining color class above MaxDegree recolors to the smallest color
+in [0, MaxDegree] its neighbors do not have. A color class of a proper coloring is an independent set,
+so its nodes never see each other's choices and can all recolor at once, on the worker pool.
+Going from k colors to MaxDegree+1 takes k-MaxDegree-1 rounds after the announce round, one per color class
+outside [0, MaxDegree], however many nodes each class holds. Classes are taken as they are, so sparse colors cost nothing extra
+ */
+func RunNaiveClasses(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	if opts.Debug % 2 == 1 {
+		fmt.Printf("Starting reduction for %s algorithm...\n", "Naive (Color Classes)")
+	}
+	net := NewNetwork(&gr, opts)
+	known := make([]map[int]int, len(gr.Nodes))
+	for i := range known {
+		known[i] = make(map[int]int)
+	}
+
+	net.SetPhase("announce")
+	net.Round(func(v *g.Node, inbox []Message, out *Outbox) {
+		out.Broadcast(v.Color)
+	})
+
+	net.SetPhase("naive-classes")
+	for _, class := range colorClassesAbove(&gr, gr.MaxDegree) {
+		net.RoundOn(class, func(v *g.Node, inbox []Message, out *Outbox) {
+			colorsFromInbox(known[v.Ind], inbox)
+			color := minFreeColor(known[v.Ind], gr.MaxDegree)
+			if color == -1 {
+				fmt.Printf("MinColor() did not return a valid value\n")
+			}
+			v.Color = color
+			out.Broadcast(color)
+		})
+	}
+	return gr, net.Stats()
+}
+
+// colorClassesAbove groups every node whose color is outside [0, maxColor] by its color, highest color first.
+// Each class lists its nodes in index order
+func colorClassesAbove(gr *g.Graph, maxColor int) [][]*g.Node {
+	byColor := make(map[int][]*g.Node)
+	for _, node := range gr.Nodes {
+		if node.Color < 0 || node.Color > maxColor {
+			byColor[node.Color] = append(byColor[node.Color], node)
+		}
+	}
+	colors := make([]int, 0, len(byColor))
+	for color := range byColor {
+		colors = append(colors, color)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(colors)))
+	classes := make([][]*g.Node, len(colors))
+	for i, color := range colors {
+		classes[i] = byColor[color]
+	}
+	return classes
+}
@@ -0,0 +1,70 @@
+package reductions
+
+import (
+	"math"
+	"sync"
+)
+
+/*
+	Bounded worker pool shared by every algorithm
+		- Pool: runs an algorithm's work over at most Size goroutines at once
+		- NewPool: sizes a Pool from RunOptions.PoolSize
+	PoolSize means the same thing in every algorithm: the most goroutines doing its work at once.
+	A PoolSize <= 0 picks the default of sqrt(n) workers. Any other size is used as given, up to one worker per node,
+	so the poolSize argument of a test directive can be swept like any other variable.
+*/
+
+// Pool bounds the number of goroutines an algorithm runs at once
+type Pool struct {
+	size int
+}
+
+// NewPool builds a Pool for a graph of numNodes nodes, with poolSize workers or the default of sqrt(numNodes) if poolSize <= 0
+func NewPool(numNodes int, poolSize int) *Pool {
+	size := poolSize
+	if size <= 0 {
+		size = int(math.Floor(math.Sqrt(float64(numNodes))))
+	}
+	if size > numNodes {
+		size = numNodes
+	}
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{size: size}
+}
+
+// Size returns the number of workers the Pool runs
+func (p *Pool) Size() int {
+	return p.size
+}
+
+// For runs body for every index in [0, n), splitting the range into contiguous chunks across the Pool's workers.
+// It returns once every call has finished, so each call to For acts as a barrier
+func (p *Pool) For(n int, body func(i int)) {
+	if n <= 0 {
+		return
+	}
+	if p.size == 1 || n == 1 {
+		for i := 0; i < n; i++ {
+			body(i)
+		}
+		return
+	}
+	chunk := (n + p.size - 1) / p.size
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start int, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				body(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package reductions
+
+import (
+	"math/rand"
+	"time"
+)
+
+/*
+	Seeded randomness shared by every randomized algorithm
+		- runSeed: the seed of a run, from RunOptions.Seed or the clock
+		- newStreamRand: a *rand.Rand for one sequential stream of a run, such as a worker or the leader
+		- nodeRandom, nodeFloat32: a random value for a node in an iteration, for work stepped on a Pool
+	No algorithm uses the global rand functions, so two runs with the same seed make the same random choices.
+	Values drawn in a Pool step come from nodeRandom rather than a shared rand.Rand, which would need locking and would
+	hand out its values in whatever order the workers happen to ask. A stream from newStreamRand is only reproducible
+	if the same work is given to it in the same order, as it is for a fixed pool size.
+*/
+
+// runSeed returns the seed a run draws its random choices from: opts.Seed, or the clock if it is 0
+func runSeed(opts RunOptions) int64 {
+	if opts.Seed != 0 {
+		return opts.Seed
+	}
+	return time.Now().UnixNano()
+}
+
+// newStreamRand returns a *rand.Rand for stream number stream of a run seeded with seed.
+// Every stream of a run gets its own generator, so streams on different goroutines never share one
+func newStreamRand(seed int64, stream int) *rand.Rand {
+	return rand.New(rand.NewSource(int64(splitMix64(uint64(seed) ^ splitMix64(uint64(stream))))))
+}
+
+// nodeRandom returns a pseudorandom number for a node in an iteration, as the SplitMix64 hash of the seed, iteration and node.
+// Unlike a shared rand.Rand it needs no locking, and unlike one per node it needs no memory
+func nodeRandom(seed int64, iter int, v int) uint64 {
+	return splitMix64(uint64(seed) ^ splitMix64(uint64(iter)<<32^uint64(v)))
+}
+
+// nodeFloat32 is nodeRandom as a float32 in [0, 1), made from its top 24 bits as rand.Float32 would be
+func nodeFloat32(seed int64, iter int, v int) float32 {
+	return float32(nodeRandom(seed, iter, v)>>40) / (1 << 24)
+}
+
+// splitMix64 is the finalizer of the SplitMix64 generator, which scrambles the bits of x
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
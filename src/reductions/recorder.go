@@ -0,0 +1,73 @@
+package reductions
+
+import (
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"sync/atomic"
+)
+
+/*
+	Per-round snapshots of a reduction for replay
+		- Recorder: collects graphs.Snapshots during a run when set in RunOptions
+	Algorithms that run on Network.Round get a snapshot after every round for free.
+	Algorithms that only charge rounds take their snapshots through Network.RecordColors at the points that matter to them.
+*/
+
+// Recorder collects a Snapshot of the coloring after each round of a run. A nil *Recorder records nothing.
+// Recording copies every color once per round, so it is meant for small graphs such as Sample02
+//		MaxSnapshots: the most snapshots to keep, or 0 for no limit. Later snapshots are dropped
+//		Snapshots: the snapshots recorded so far, in order
+type Recorder struct {
+	MaxSnapshots int
+	Snapshots    []g.Snapshot
+}
+
+// Record appends a Snapshot unless the Recorder is nil or full
+func (r *Recorder) Record(snap g.Snapshot) {
+	if r == nil || (r.MaxSnapshots > 0 && len(r.Snapshots) >= r.MaxSnapshots) {
+		return
+	}
+	r.Snapshots = append(r.Snapshots, snap)
+}
+
+// SetPhase labels every snapshot taken after each Round from now on, e.g. "linial" or "naive"
+func (n *Network) SetPhase(phase string) {
+	n.phase = phase
+}
+
+// Recording returns whether snapshots are being recorded, so algorithms can skip building them otherwise
+func (n *Network) Recording() bool {
+	return n.recorder != nil
+}
+
+// RecordColors records a snapshot for algorithms that charge their rounds instead of running them on Round.
+// roundOffset is added to the rounds recorded so far, for snapshots taken before the rounds they belong to are charged.
+// edges limits the frame to a subgraph such as a single forest, or is nil for the whole graph
+func (n *Network) RecordColors(phase string, roundOffset int, colors []int, edges [][2]int) {
+	if n.recorder == nil {
+		return
+	}
+	n.recorder.Record(g.Snapshot{
+		Round:  int(atomic.LoadInt64(&n.rounds)) + roundOffset,
+		Phase:  phase,
+		Colors: colors,
+		Edges:  edges,
+	})
+}
+
+// recordRound records the coloring of the whole graph after a Round
+func (n *Network) recordRound() {
+	if n.recorder == nil {
+		return
+	}
+	n.RecordColors(n.phase, 0, n.colors(), nil)
+}
+
+// colors copies the current color of every node, from whichever form of the graph the Network runs over
+func (n *Network) colors() []int {
+	if n.csr != nil {
+		colors := make([]int, len(n.csr.Colors))
+		copy(colors, n.csr.Colors)
+		return colors
+	}
+	return g.ColorsOf(n.gr)
+}
@@ -1,40 +1,224 @@
 package reductions
 
 import (
+	"errors"
+	"fmt"
 	g "github.com/thomaseb191/go-coloring/graphs"
-	"log"
+	"sort"
+	"strings"
 )
 
-// AllAlgIds - A list of all valid algorithm IDs for when t.RunTest is given an empty array.
-var AllAlgIds = []int{0, 1, 2, 3} //TODO: ADD ADDITIONAL IDS
-const NumAlgos = 4                //TODO: MAKE SURE THIS MATCHES THE LENGTH OF ABOVE
+/*
+	Useful functions offered by this file:
+		- Register: adds a Reducer to the registry under a numeric algorithm ID
+		- Lookup: retrieves a registered Reducer by its algorithm ID
+		- LookupShortID: retrieves the algorithm ID for a Reducer's ShortID
+		- RunReduction: runs a registered Reducer by its algorithm ID
+		- RunReductionCSR: runs a registered Reducer by its algorithm ID on a CSR graph
+*/
 
-// RunReduction calls the respective color-reducing algorithm for a graph, algorithm id, number of worker pools, and debug setting
+// Capability is a set of flags describing how a Reducer runs
+type Capability int
+
+const (
+	// Parallel marks a Reducer that spreads its work across goroutines
+	Parallel Capability = 1 << iota
+	// Randomized marks a Reducer whose output depends on random choices
+	Randomized
+	// NativeCSR marks a Reducer that implements CSRReducer, and so runs on a CSR without converting it to a Graph
+	NativeCSR
+)
+
+// Has returns whether every flag in other is set in c
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// String lists the set flags, e.g. "parallel,randomized", or "sequential" if none are set
+func (c Capability) String() string {
+	var flags []string
+	if c.Has(Parallel) {
+		flags = append(flags, "parallel")
+	}
+	if c.Has(Randomized) {
+		flags = append(flags, "randomized")
+	}
+	if c.Has(NativeCSR) {
+		flags = append(flags, "csr")
+	}
+	if len(flags) == 0 {
+		return "sequential"
+	}
+	return strings.Join(flags, ",")
+}
+
+// Reducer is a color-reducing algorithm that takes a colored Graph to a MaxDegree+1 coloring
+//		Name: the display name used in test names and trend charts
+//		ShortID: a short lowercase identifier that may be used in place of the numeric ID in test directives
+//		Capabilities: the flags describing how the algorithm runs
+//		Run: runs the algorithm on a graph it owns, returning the reduced graph and its LOCAL-model round and message counts
+type Reducer interface {
+	Name() string
+	ShortID() string
+	Capabilities() Capability
+	Run(gr g.Graph, opts RunOptions) (g.Graph, RunStats)
+}
+
+// CSRReducer is a Reducer that can also run directly on a CSR graph, changing only its Colors
+type CSRReducer interface {
+	Reducer
+	RunCSR(c *g.CSR, opts RunOptions) RunStats
+}
+
+// reducerFunc adapts a plain algorithm function to the Reducer interface
+type reducerFunc struct {
+	name    string
+	shortID string
+	caps    Capability
+	run     func(gr g.Graph, opts RunOptions) (g.Graph, RunStats)
+}
+
+func (r reducerFunc) Name() string             { return r.name }
+func (r reducerFunc) ShortID() string          { return r.shortID }
+func (r reducerFunc) Capabilities() Capability { return r.caps }
+func (r reducerFunc) Run(gr g.Graph, opts RunOptions) (g.Graph, RunStats) {
+	return r.run(gr, opts)
+}
+
+// NewReducer wraps an algorithm function with the metadata needed to register it
+func NewReducer(name string, shortID string, caps Capability, run func(gr g.Graph, opts RunOptions) (g.Graph, RunStats)) Reducer {
+	return reducerFunc{name: name, shortID: shortID, caps: caps, run: run}
+}
+
+// csrReducerFunc adapts a pair of algorithm functions, one per graph form, to the CSRReducer interface
+type csrReducerFunc struct {
+	reducerFunc
+	runCSR func(c *g.CSR, opts RunOptions) RunStats
+}
+
+func (r csrReducerFunc) RunCSR(c *g.CSR, opts RunOptions) RunStats {
+	return r.runCSR(c, opts)
+}
+
+// NewCSRReducer is NewReducer for an algorithm that also has a CSR implementation. The NativeCSR flag is added to caps
+func NewCSRReducer(name string, shortID string, caps Capability, run func(gr g.Graph, opts RunOptions) (g.Graph, RunStats), runCSR func(c *g.CSR, opts RunOptions) RunStats) Reducer {
+	return csrReducerFunc{
+		reducerFunc: reducerFunc{name: name, shortID: shortID, caps: caps | NativeCSR, run: run},
+		runCSR:      runCSR,
+	}
+}
+
+// ErrUnknownAlgorithm is returned when running an algorithm ID that was never registered
+var ErrUnknownAlgorithm = errors.New("no such algorithm")
+
+// registry maps algorithm IDs to their Reducers
+var registry = make(map[int]Reducer)
+
+// AllAlgIds - A sorted list of all registered algorithm IDs for when t.RunTest is given an empty array.
+var AllAlgIds []int
+
+// init registers every built-in algorithm. New algorithms only need to be added here
+func init() {
+	Register(0, NewCSRReducer("Naive", "naive", 0, RunNaive, runNaiveCSR))
+	Register(1, NewReducer("Kuhn-Wattenhofer", "kw", Parallel, kwReduction))
+	Register(2, NewReducer("Cole-Vishkin", "cv", Parallel|Randomized, CVReduction))
+	Register(3, NewCSRReducer("Distributed Largest-First", "dlf", Parallel|Randomized, dlfShared, dlfSharedCSR))
+	Register(4, NewReducer("Linial + Kuhn-Wattenhofer", "linial", Parallel, linialReduction))
+	Register(5, NewReducer("Distributed Largest-First (Message Passing)", "dlfmp", Parallel|Randomized, dlfMessagePassing))
+	Register(6, NewReducer("Johansson Random Trials", "johansson", Parallel|Randomized, johanssonReduction))
+	Register(7, NewReducer("Naive (Color Classes)", "naiveclasses", Parallel, RunNaiveClasses))
+	Register(8, NewReducer("Cole-Vishkin 3-Coloring", "cv3", Parallel, CVThreeColoring))
+}
+
+// Register adds a Reducer under the given algorithm ID. It panics if the ID or ShortID is already taken
+func Register(id int, red Reducer) {
+	if _, ok := registry[id]; ok {
+		panic(fmt.Sprintf("reductions: algorithm ID %d registered twice", id))
+	}
+	if _, ok := LookupShortID(red.ShortID()); ok {
+		panic(fmt.Sprintf("reductions: short ID %s registered twice", red.ShortID()))
+	}
+	registry[id] = red
+	AllAlgIds = append(AllAlgIds, id)
+	sort.Ints(AllAlgIds)
+}
+
+// Lookup returns the Reducer registered under an algorithm ID
+func Lookup(id int) (Reducer, bool) {
+	red, ok := registry[id]
+	return red, ok
+}
+
+// LookupShortID returns the algorithm ID of the Reducer registered with the given ShortID
+func LookupShortID(shortID string) (int, bool) {
+	for id, red := range registry {
+		if red.ShortID() == shortID {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// AlgoNames returns a map of every registered algorithm ID to its display name
+func AlgoNames() map[int]string {
+	names := make(map[int]string)
+	for id, red := range registry {
+		names[id] = red.Name()
+	}
+	return names
+}
+
+// RunReduction calls the respective color-reducing algorithm for a graph, algorithm id, and run options
 // 		gr: a graph that the algorithm will own
-// 		id: an ID mapping to an algorithm
-// 		poolSize: the number of worker goroutines allowed for parallel algorithms
-// 		debug: 0 if just generate output, 1 if allow prints, 2 if just graph and output, 3 if allow graph and prints
-func RunReduction(gr g.Graph, id int, poolSize int, debug int) (g.Graph, string) {
-	var outGraph g.Graph
-	var algoName string
-
-	switch id {
-	case 0:
-		outGraph = RunNaive(gr, poolSize, debug)
-		algoName = "Naive"
-	case 1:
-		outGraph = kwReduction(gr, poolSize, debug)
-		algoName = "Kuhn-Wattenhofer"
-	case 2:
-		outGraph = CVReduction(gr, poolSize, debug)
-		algoName = "Cole-Vishkin"
-	case 3:
-		outGraph = dlfShared(gr, poolSize, debug)
-		algoName = "Distributed Largest-First"
-	//TODO: ADD ADDITIONAL ALGORITHMS
-
-	default:
-		log.Fatalf("No such algorithm found for %d.\n", id)
-	}
-	return outGraph, algoName
+// 		id: an ID mapping to a registered algorithm
+// 		opts: the number of worker goroutines allowed for parallel algorithms and the debug setting
+// It returns the reduced graph, the algorithm's name, and its LOCAL-model round and message counts,
+// or ErrUnknownAlgorithm if no algorithm is registered under id.
+// If the algorithm fell back to another one, the name says so, e.g. "Cole-Vishkin 3-Coloring (fallback to Cole-Vishkin)".
+// If opts.Recorder is set, the initial and final colorings are recorded around the algorithm's own snapshots
+func RunReduction(gr g.Graph, id int, opts RunOptions) (g.Graph, string, RunStats, error) {
+	red, ok := Lookup(id)
+	if !ok {
+		return gr, "", RunStats{}, fmt.Errorf("%w: %d", ErrUnknownAlgorithm, id)
+	}
+	opts.Recorder.Record(g.Snapshot{Phase: "initial", Colors: g.ColorsOf(&gr)})
+	outGraph, stats := red.Run(gr, opts)
+	opts.Recorder.Record(g.Snapshot{Round: stats.Rounds, Phase: "final", Colors: g.ColorsOf(&outGraph)})
+	return outGraph, runName(red, stats), stats, nil
+}
+
+// runName is the name of a Reducer for a run of it, which names the algorithm it fell back to if it did
+func runName(red Reducer, stats RunStats) string {
+	if stats.Fallback == "" {
+		return red.Name()
+	}
+	fallbackName := stats.Fallback
+	if id, ok := LookupShortID(stats.Fallback); ok {
+		fallbackName = registry[id].Name()
+	}
+	return fmt.Sprintf("%s (fallback to %s)", red.Name(), fallbackName)
+}
+
+// RunReductionCSR calls the respective color-reducing algorithm for a CSR graph, algorithm id, and run options.
+// The algorithm colors c in place. Algorithms without a CSR implementation run on a Graph converted from c,
+// and that conversion is part of their cost.
+// It returns the algorithm's name and its LOCAL-model round and message counts, or ErrUnknownAlgorithm if no algorithm is registered under id
+func RunReductionCSR(c *g.CSR, id int, opts RunOptions) (string, RunStats, error) {
+	red, ok := Lookup(id)
+	if !ok {
+		return "", RunStats{}, fmt.Errorf("%w: %d", ErrUnknownAlgorithm, id)
+	}
+	opts.Recorder.Record(g.Snapshot{Phase: "initial", Colors: append([]int(nil), c.Colors...)})
+	var stats RunStats
+	if csrRed, ok := red.(CSRReducer); ok {
+		stats = csrRed.RunCSR(c, opts)
+	} else {
+		var outGraph g.Graph
+		outGraph, stats = red.Run(c.ToGraph(), opts)
+		for _, node := range outGraph.Nodes {
+			c.Colors[node.Ind] = node.Color
+		}
+	}
+	opts.Recorder.Record(g.Snapshot{Round: stats.Rounds, Phase: "final", Colors: append([]int(nil), c.Colors...)})
+	return runName(red, stats), stats, nil
 }
@@ -0,0 +1,210 @@
+package testHarness
+
+import (
+	"bufio"
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+	Reading and writing the DIMACS .col format used by the classic coloring benchmarks
+		c <comment>
+		p edge <numNodes> <numEdges>
+		e <u> <v>
+		n <u> <color>
+	Nodes are 1-indexed and named by their number. An n line gives a node an initial color. Edges are undirected, and an edge listed in both directions is only added once.
+	An edge listed again in the same direction is skipped and counted in the ParseReport's DuplicateEdges.
+	See formats.go for how a graph file's format is chosen.
+*/
+
+// IsDIMACS returns whether a file is in DIMACS format, either by its .col extension or by its first line being a c or p line
+func IsDIMACS(fileName string) (bool, error) {
+	if strings.EqualFold(filepath.Ext(fileName), ".col") {
+		return true, nil
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	first := scanner.Text()
+	return strings.HasPrefix(first, "c ") || first == "c" || strings.HasPrefix(first, "p edge ") || strings.HasPrefix(first, "p col "), nil
+}
+
+// ParseDIMACS takes a DIMACS .col fileName and whether or not colors should be initialized to their index in the node array.
+// MaxDegree is computed from the edges. Comment lines become the Graph's Description, and n lines set colors unless colorInit is set.
+// Returns a *ParseError for a missing or malformed p line, malformed e or n lines, out of range node numbers, or self-loops
+func ParseDIMACS(fileName string, colorInit bool) (g.Graph, error) {
+	return parseDIMACS(fileName, colorInit, &ParseReport{Format: FormatDIMACS})
+}
+
+// parseDIMACS is ParseDIMACS, counting the nodes given a color and the duplicate edges skipped in report
+func parseDIMACS(fileName string, colorInit bool, report *ParseReport) (g.Graph, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return g.Graph{}, err
+	}
+	defer f.Close()
+
+	scanner := newLineScanner(f)
+
+	var nodeList []*g.Node
+	var comments []string
+	seen := make(map[[2]int]bool)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "c":
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "c")))
+		case "p":
+			if nodeList != nil || len(fields) != 4 {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			numNodes, err := strconv.Atoi(fields[2])
+			if err != nil || numNodes < 0 {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			nodeList = make([]*g.Node, numNodes)
+			for i := range nodeList {
+				nodeList[i] = &g.Node{Name: strconv.Itoa(i + 1), Ind: i}
+				if colorInit {
+					nodeList[i].Color = i
+				}
+			}
+		case "e":
+			if nodeList == nil {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			if len(fields) < 3 {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+			}
+			u, errU := strconv.Atoi(fields[1])
+			v, errV := strconv.Atoi(fields[2])
+			if errU != nil || errV != nil {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+			}
+			if u < 1 || u > len(nodeList) {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: fields[1], Err: g.ErrUnknownNeighbor}
+			}
+			if v < 1 || v > len(nodeList) {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: fields[2], Err: g.ErrUnknownNeighbor}
+			}
+			if u == v {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: fields[1], Err: ErrSelfLoop}
+			}
+			if seen[[2]int{u, v}] {
+				report.DuplicateEdges++
+				continue
+			}
+			seen[[2]int{u, v}] = true
+			if seen[[2]int{v, u}] {
+				// The same edge in the other direction, which some benchmarks list for every edge
+				continue
+			}
+			nodeU, nodeV := nodeList[u-1], nodeList[v-1]
+			nodeU.Neighbors = append(nodeU.Neighbors, nodeV)
+			nodeV.Neighbors = append(nodeV.Neighbors, nodeU)
+		case "n":
+			if nodeList == nil {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			if len(fields) != 3 {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+			}
+			u, errU := strconv.Atoi(fields[1])
+			color, errColor := strconv.Atoi(fields[2])
+			if errU != nil || errColor != nil {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+			}
+			if u < 1 || u > len(nodeList) {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: fields[1], Err: g.ErrUnknownNeighbor}
+			}
+			if !colorInit {
+				nodeList[u-1].Color = color
+			}
+			report.ColoredNodes++
+		default:
+			// Other DIMACS line types (x, ...) carry no edges and are skipped
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return g.Graph{}, &ParseError{File: fileName, Line: lineNum + 1, Err: err}
+	}
+	if nodeList == nil {
+		return g.Graph{}, &ParseError{File: fileName, Err: fmt.Errorf("%w: no p line", ErrBadHeader)}
+	}
+
+	maxDegree := 0
+	for _, node := range nodeList {
+		if len(node.Neighbors) > maxDegree {
+			maxDegree = len(node.Neighbors)
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	description := strings.Join(comments, " ")
+	if description == "" {
+		description = fmt.Sprintf("DIMACS graph %s", name)
+	}
+	return g.Graph{
+		Name:        name,
+		Description: description,
+		MaxDegree:   maxDegree,
+		Nodes:       nodeList,
+	}, nil
+}
+
+// WriteDIMACS writes a Graph in DIMACS .col format. Nodes are numbered by their position in gr.Nodes, starting at 1,
+// and the Graph's Name and Description are written as comments
+func WriteDIMACS(gr *g.Graph, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	position := make(map[*g.Node]int, len(gr.Nodes))
+	numEdges := 0
+	for i, node := range gr.Nodes {
+		position[node] = i + 1
+		numEdges += len(node.Neighbors)
+	}
+
+	fmt.Fprintf(bw, "c %s\n", gr.Name)
+	if gr.Description != "" {
+		fmt.Fprintf(bw, "c %s\n", gr.Description)
+	}
+	fmt.Fprintf(bw, "p edge %d %d\n", len(gr.Nodes), numEdges/2)
+	for i, node := range gr.Nodes {
+		for _, neighbor := range node.Neighbors {
+			if position[neighbor] > i+1 {
+				fmt.Fprintf(bw, "e %d %d\n", i+1, position[neighbor])
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteDIMACSFile writes a Graph to fileName in DIMACS .col format
+func WriteDIMACSFile(gr *g.Graph, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := WriteDIMACS(gr, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+package testHarness
+
+import (
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"os"
+	"strings"
+	"unicode"
+)
+
+/*
+	Reading edge lists, as the SNAP and KONECT network collections are published
+		# <comment>
+		<u> <v> [anything else]
+	Nodes may be named by any token, not only numbers, and are indexed in the order they first appear.
+	The ends of an edge are separated by whitespace or a comma, and any further fields, such as weights or timestamps, are ignored.
+	Lines starting with # or % are comments. Edges are undirected, so a directed graph is read as its underlying undirected graph.
+*/
+
+// ParseEdgeList takes an edge list fileName and whether or not colors should be initialized to their index in the node array.
+// MaxDegree is computed from the edges, and comment lines become the Graph's Description.
+// Self-loops and edges listed twice in the same direction are skipped and counted in the ParseReport.
+// Returns a *ParseError for a line without two nodes
+func ParseEdgeList(fileName string, colorInit bool) (g.Graph, ParseReport, error) {
+	report := ParseReport{Format: FormatEdgeList}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return g.Graph{}, report, err
+	}
+	defer f.Close()
+
+	scanner := newLineScanner(f)
+
+	var names []string
+	index := make(map[string]int)
+	indexOf := func(name string) int {
+		ind, ok := index[name]
+		if !ok {
+			ind = len(names)
+			index[name] = ind
+			names = append(names, name)
+		}
+		return ind
+	}
+
+	var comments []string
+	arcs := arcSet{report: &report}
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] == '#' || line[0] == '%' {
+			comments = append(comments, strings.TrimSpace(line[1:]))
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		if len(fields) < 2 {
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+		}
+		arcs.add(indexOf(fields[0]), indexOf(fields[1]))
+	}
+	if err := scanner.Err(); err != nil {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum + 1, Err: err}
+	}
+
+	nodeList, maxDegree := arcs.build(names, colorInit, false)
+	name, description := describeGraph(fileName, comments, "Edge list")
+	return g.Graph{
+		Name:        name,
+		Description: description,
+		MaxDegree:   maxDegree,
+		Nodes:       nodeList,
+	}, report, nil
+}
@@ -0,0 +1,74 @@
+package testHarness
+
+import (
+	"errors"
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+)
+
+/*
+	Errors returned while parsing graph files, initial colorings and test directives.
+	Every error wraps one of the Err* sentinels below (or graphs.ErrDirectedEdge / graphs.ErrUnknownNeighbor),
+	so callers can check the kind with errors.Is and the location with errors.As on *ParseError.
+*/
+
+var (
+	// ErrBadHeader is returned when the name, description or max degree header lines are missing or malformed
+	ErrBadHeader = errors.New("bad header")
+	// ErrBadLine is returned when a node line is not of the form Name:Neighbor1,Neighbor2
+	ErrBadLine = errors.New("bad line")
+	// ErrDuplicateNode is returned when a node is defined twice
+	ErrDuplicateNode = errors.New("duplicate node")
+	// ErrDegreeTooLarge is returned when a node has more neighbors than the declared max degree
+	ErrDegreeTooLarge = errors.New("degree over declared max")
+	// ErrSelfLoop is returned when a node is its own neighbor, which no proper coloring allows
+	ErrSelfLoop = errors.New("self-loop")
+	// ErrBadDirective is returned when a test directive or its arguments cannot be parsed
+	ErrBadDirective = errors.New("bad directive")
+	// ErrMissingColor is returned when an initial coloring taken from a file leaves a node uncolored
+	ErrMissingColor = errors.New("node without initial color")
+	// ErrImproperColoring is returned when the initial coloring gives two neighbors the same color, or a node a negative one
+	ErrImproperColoring = errors.New("improper initial coloring")
+	// ErrUnexpectedSuccess is returned when a directive with expect=kind parses without that error
+	ErrUnexpectedSuccess = errors.New("expected error did not occur")
+)
+
+// errorKinds maps the names accepted by the expect= directive option to their errors
+var errorKinds = map[string]error{
+	"bad-header":       ErrBadHeader,
+	"bad-line":         ErrBadLine,
+	"duplicate-node":   ErrDuplicateNode,
+	"degree-too-large": ErrDegreeTooLarge,
+	"directed-edge":    g.ErrDirectedEdge,
+	"unknown-neighbor": g.ErrUnknownNeighbor,
+	"self-loop":        ErrSelfLoop,
+	"missing-color":    ErrMissingColor,
+	"improper-coloring": ErrImproperColoring,
+}
+
+// ParseError records where in a file parsing failed
+//		File: the name of the file being parsed
+//		Line: the 1-indexed line number, or 0 if the error is not tied to a line
+//		Node: the name of the offending node, if any
+//		Err: the underlying error, usually one of the Err* sentinels or a *graphs.GraphError
+type ParseError struct {
+	File string
+	Line int
+	Node string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	msg := e.File
+	if e.Line > 0 {
+		msg += fmt.Sprintf(":%d", e.Line)
+	}
+	if e.Node != "" {
+		msg += fmt.Sprintf(": node %s", e.Node)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,258 @@
+package testHarness
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	Choosing a reader for a graph file
+		- ParseGraphFile: parse a graph file in any supported format
+		- ParseGraphFileWithReport: ParseGraphFile, also reporting the self-loops and duplicate edges it skipped
+		- DetectFormat: pick a file's format from its extension, or else its first line
+	A graph file may be given as format:fileName to skip detection, e.g. metis:../res/road.txt. The formats are
+		adj: the Name:Neighbor1,Neighbor2 format of the files in res (see parse.go)
+		dimacs: DIMACS .col (see dimacs.go)
+		edgelist: one edge per line, as in SNAP and KONECT (see edgelist.go)
+		metis: METIS .graph (see metis.go)
+		mtx: Matrix Market coordinate matrices, as in SuiteSparse (see matrixmarket.go)
+	The edge list, METIS and Matrix Market readers skip self-loops and duplicate edges instead of failing on them,
+	since real-world networks and matrix diagonals are full of both, and count them in a ParseReport.
+*/
+
+const (
+	// FormatAdj is the Name:Neighbor1,Neighbor2 format with a three line header
+	FormatAdj = "adj"
+	// FormatDIMACS is the DIMACS .col format
+	FormatDIMACS = "dimacs"
+	// FormatEdgeList is a list of edges, one per line
+	FormatEdgeList = "edgelist"
+	// FormatMETIS is the METIS .graph format
+	FormatMETIS = "metis"
+	// FormatMatrixMarket is the Matrix Market .mtx coordinate format
+	FormatMatrixMarket = "mtx"
+)
+
+// formatExtensions maps the file extensions each format is detected by to the format
+var formatExtensions = map[string]string{
+	".col":      FormatDIMACS,
+	".edges":    FormatEdgeList,
+	".edgelist": FormatEdgeList,
+	".el":       FormatEdgeList,
+	".tsv":      FormatEdgeList,
+	".graph":    FormatMETIS,
+	".metis":    FormatMETIS,
+	".mtx":      FormatMatrixMarket,
+}
+
+// ParseReport describes what a reader skipped while building a Graph
+//		Format: the format the file was read as
+//		SelfLoops: the number of edges from a node to itself, which no proper coloring allows
+//		DuplicateEdges: the number of edges listed again in the same direction
+//		OneWayEdges: for METIS, which lists every edge from both ends, the number of edges listed from only one end.
+//			They are still added in both directions
+//		ColoredNodes: the number of nodes the file gives an initial color, which only adj and DIMACS files can
+type ParseReport struct {
+	Format string
+	SelfLoops int
+	DuplicateEdges int
+	OneWayEdges int
+	ColoredNodes int
+}
+
+// Clean returns whether nothing in the file was skipped or repaired
+func (r ParseReport) Clean() bool {
+	return r.SelfLoops == 0 && r.DuplicateEdges == 0 && r.OneWayEdges == 0
+}
+
+func (r ParseReport) String() string {
+	return fmt.Sprintf("%s: skipped %d self-loops and %d duplicate edges, repaired %d one-way edges", r.Format, r.SelfLoops, r.DuplicateEdges, r.OneWayEdges)
+}
+
+// ParseGraphFile parses a graph file in whichever format it is written in, given as a format: prefix or found by DetectFormat
+func ParseGraphFile(graphFile string, colorInit bool) (g.Graph, error) {
+	gr, _, err := ParseGraphFileWithReport(graphFile, colorInit)
+	return gr, err
+}
+
+// ParseGraphFileWithReport is ParseGraphFile, also returning what the reader skipped.
+// The adj and DIMACS readers fail on self-loops instead, so an adj report is always clean,
+// and a DIMACS report only counts the edges listed twice in the same direction as DuplicateEdges
+func ParseGraphFileWithReport(graphFile string, colorInit bool) (g.Graph, ParseReport, error) {
+	format, fileName := SplitFormat(graphFile)
+	if format == "" {
+		var err error
+		format, err = DetectFormat(fileName)
+		if err != nil {
+			return g.Graph{}, ParseReport{}, err
+		}
+	}
+	report := ParseReport{Format: format}
+	var gr g.Graph
+	var err error
+	switch format {
+	case FormatAdj:
+		gr, err = parseFile(fileName, colorInit, &report)
+	case FormatDIMACS:
+		gr, err = parseDIMACS(fileName, colorInit, &report)
+	case FormatEdgeList:
+		gr, report, err = ParseEdgeList(fileName, colorInit)
+	case FormatMETIS:
+		gr, report, err = ParseMETIS(fileName, colorInit)
+	case FormatMatrixMarket:
+		gr, report, err = ParseMatrixMarket(fileName, colorInit)
+	}
+	return gr, report, err
+}
+
+// SplitFormat splits a graph file given as format:fileName into its format and file name.
+// If graphFile has no known format prefix, the format is "" and the file name is graphFile
+func SplitFormat(graphFile string) (string, string) {
+	splitted := strings.SplitN(graphFile, ":", 2)
+	if len(splitted) == 2 {
+		switch splitted[0] {
+		case FormatAdj, FormatDIMACS, FormatEdgeList, FormatMETIS, FormatMatrixMarket:
+			return splitted[0], splitted[1]
+		}
+	}
+	return "", graphFile
+}
+
+// DetectFormat returns the format of a file by its extension, see formatExtensions, or else by its first line:
+// %%MatrixMarket starts a Matrix Market file, a c or p line a DIMACS file, and a # comment an edge list.
+// Anything else is taken to be adj. METIS files are only detected by their extension
+func DetectFormat(fileName string) (string, error) {
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return format, nil
+	}
+	isDimacs, err := IsDIMACS(fileName)
+	if err != nil {
+		return "", err
+	}
+	if isDimacs {
+		return FormatDIMACS, nil
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := newLineScanner(f)
+	if !scanner.Scan() {
+		return FormatAdj, scanner.Err()
+	}
+	first := scanner.Text()
+	switch {
+	case strings.HasPrefix(first, "%%MatrixMarket"):
+		return FormatMatrixMarket, nil
+	case strings.HasPrefix(first, "#"):
+		return FormatEdgeList, nil
+	}
+	return FormatAdj, nil
+}
+
+// arcSet collects the edges of a graph file as they are listed, as arcs from one node's index to another's
+//		arcs: every arc, packed as from<<32 | to
+//		report: where self-loops are counted as they are added, and duplicates once the Graph is built
+type arcSet struct {
+	arcs []uint64
+	report *ParseReport
+}
+
+// add adds the arc from u to v, counting it as a self-loop instead if u == v
+func (s *arcSet) add(u int, v int) {
+	if u == v {
+		s.report.SelfLoops++
+		return
+	}
+	s.arcs = append(s.arcs, uint64(u)<<32|uint64(v))
+}
+
+// build builds the undirected Graph of every arc, with one Node per name, named and indexed in order.
+// An arc listed twice counts as a duplicate edge, while an arc and its reverse are the same edge.
+// If countOneWay is set, edges with only one of their two arcs are counted as one-way edges.
+// Every Node's neighbors are sorted by index, and share a single array as in g.DeepCopy
+func (s *arcSet) build(names []string, colorInit bool, countOneWay bool) ([]*g.Node, int) {
+	sort.Slice(s.arcs, func(a, b int) bool { return s.arcs[a] < s.arcs[b] })
+	edges := make([]uint64, 0, len(s.arcs))
+	for i, arc := range s.arcs {
+		if i > 0 && arc == s.arcs[i-1] {
+			s.report.DuplicateEdges++
+			continue
+		}
+		u, v := arc>>32, arc&(1<<32-1)
+		if u > v {
+			u, v = v, u
+		}
+		edges = append(edges, u<<32|v)
+	}
+	s.arcs = nil
+
+	// Each edge is now listed once per arc it had, so a lone entry is an edge with a single arc
+	sort.Slice(edges, func(a, b int) bool { return edges[a] < edges[b] })
+	unique := edges[:0]
+	for i, edge := range edges {
+		if i > 0 && edge == edges[i-1] {
+			continue
+		}
+		if countOneWay && (i+1 == len(edges) || edges[i+1] != edge) {
+			s.report.OneWayEdges++
+		}
+		unique = append(unique, edge)
+	}
+
+	degree := make([]int, len(names))
+	for _, edge := range unique {
+		degree[edge>>32]++
+		degree[edge&(1<<32-1)]++
+	}
+	nodes := make([]g.Node, len(names))
+	nodeList := make([]*g.Node, len(names))
+	adj := make([]*g.Node, 2*len(unique))
+	maxDegree := 0
+	start := 0
+	for i := range nodes {
+		nodes[i] = g.Node{Name: names[i], Ind: i}
+		if colorInit {
+			nodes[i].Color = i
+		}
+		nodeList[i] = &nodes[i]
+		nodes[i].Neighbors = adj[start:start:start+degree[i]]
+		start += degree[i]
+		if degree[i] > maxDegree {
+			maxDegree = degree[i]
+		}
+	}
+	// Edges are sorted by their smaller end, so every node gets its smaller neighbors before its larger ones, each in order
+	for _, edge := range unique {
+		u, v := nodeList[edge>>32], nodeList[edge&(1<<32-1)]
+		u.Neighbors = append(u.Neighbors, v)
+		v.Neighbors = append(v.Neighbors, u)
+	}
+	return nodeList, maxDegree
+}
+
+// numberedNames returns the names 1 to n, for formats whose nodes are numbered from 1
+func numberedNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = strconv.Itoa(i + 1)
+	}
+	return names
+}
+
+// describeGraph returns a Graph's name, from its file name, and description, from its comments or else its format
+func describeGraph(fileName string, comments []string, formatName string) (string, string) {
+	name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	description := strings.Join(comments, " ")
+	if description == "" {
+		description = fmt.Sprintf("%s graph %s", formatName, name)
+	}
+	return name, description
+}
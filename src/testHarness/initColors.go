@@ -0,0 +1,232 @@
+package testHarness
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	r "github.com/thomaseb191/go-coloring/reductions"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+	Choosing the initial coloring the algorithms reduce, with the init= directive option
+		index: every node's color is its index in the Graph's Nodes, as set by g.RunColorInit (the default)
+		file: the colors given in the graph file itself, as Name=Color:Neighbors in adj files or n <node> <color> lines in DIMACS
+		sidecar: the colors in a separate file of name color lines, by default the graph file with its extension replaced by .colors,
+			or any other file with sidecar:fileName
+		random: distinct random IDs below 2^62, or below 2^b with random:b, drawn from the directive's seed
+		algo:<id>: the output of another algorithm, by ID or short ID, run on the index coloring with the directive's settings
+	Whatever its source, the initial coloring must be proper, so every algorithm starts from a valid k-coloring.
+*/
+
+const (
+	// InitIndex colors every node with its index
+	InitIndex = "index"
+	// InitFile takes the colors given in the graph file
+	InitFile = "file"
+	// InitSidecar reads the colors from a .colors file
+	InitSidecar = "sidecar"
+	// InitRandom draws distinct random IDs
+	InitRandom = "random"
+	// InitAlgo takes another algorithm's output
+	InitAlgo = "algo"
+)
+
+// maxInitBits is the widest random ID. Linial's polynomials are evaluated in 64 bits, and stay exact for colors below 2^62
+const maxInitBits = 62
+
+// sidecarExt is the extension of the coloring file read by init=sidecar
+const sidecarExt = ".colors"
+
+// checkInit returns an error if init is not a source applyInit accepts. Sources that depend on the graph are checked when it is read
+func checkInit(init string) error {
+	splitted := strings.SplitN(init, ":", 2)
+	switch splitted[0] {
+	case InitIndex, InitFile:
+		if len(splitted) == 1 {
+			return nil
+		}
+	case InitSidecar:
+		if len(splitted) == 1 || splitted[1] != "" {
+			return nil
+		}
+	case InitRandom:
+		if len(splitted) == 1 {
+			return nil
+		}
+		if _, err := parseInitBits(splitted[1]); err == nil {
+			return nil
+		}
+	case InitAlgo:
+		if len(splitted) == 2 {
+			if _, err := lookupInitAlgo(splitted[1]); err == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: unknown initial coloring %s", ErrBadDirective, init)
+}
+
+// applyInit colors initGraph as td.Init says, read from initially uncolored nodes except for init=file.
+// report is what the graph file's reader found, and fileName the graph file without its format prefix
+func applyInit(initGraph *g.Graph, td TestDirective, fileName string, report ParseReport) error {
+	init := td.Init
+	if init == "" {
+		init = InitIndex
+	}
+	splitted := strings.SplitN(init, ":", 2)
+	switch splitted[0] {
+	case InitFile:
+		if report.ColoredNodes < len(initGraph.Nodes) {
+			return &ParseError{File: fileName,
+				Err: fmt.Errorf("%w: %d of %d nodes are colored", ErrMissingColor, report.ColoredNodes, len(initGraph.Nodes))}
+		}
+		return nil
+	case InitSidecar:
+		sidecar := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + sidecarExt
+		if len(splitted) == 2 {
+			sidecar = splitted[1]
+		}
+		return readSidecar(initGraph, sidecar)
+	case InitRandom:
+		numBits := maxInitBits
+		if len(splitted) == 2 {
+			numBits, _ = parseInitBits(splitted[1])
+		}
+		return randomInit(initGraph, numBits, td.Seed)
+	case InitAlgo:
+		id, err := lookupInitAlgo(splitted[1])
+		if err != nil {
+			return err
+		}
+		g.RunColorInit(initGraph)
+		opts := r.RunOptions{PoolSize: td.PoolSize, Congest: td.Congest, Seed: td.Seed, Topology: td.Topology}
+		outGraph, _, _, err := r.RunReduction(g.DeepCopy(initGraph), id, opts)
+		if err != nil {
+			return err
+		}
+		for i, node := range outGraph.Nodes {
+			initGraph.Nodes[i].Color = node.Color
+		}
+		return nil
+	}
+	g.RunColorInit(initGraph)
+	return nil
+}
+
+// parseInitBits parses the b of random:b, which must leave room for at least as many IDs as a graph could need
+func parseInitBits(val string) (int, error) {
+	conv, err := strconv.Atoi(val)
+	if err != nil || conv < 1 || conv > maxInitBits {
+		return 0, fmt.Errorf("%w: random:%s is not between 1 and %d bits", ErrBadDirective, val, maxInitBits)
+	}
+	return conv, nil
+}
+
+// lookupInitAlgo returns the ID of the algorithm named by algo:<id>, given as a number or a short ID
+func lookupInitAlgo(val string) (int, error) {
+	id, err := strconv.Atoi(val)
+	if err != nil {
+		var ok bool
+		if id, ok = r.LookupShortID(val); !ok {
+			return 0, fmt.Errorf("%w: algo:%s is not a registered algorithm", ErrBadDirective, val)
+		}
+	}
+	if _, ok := r.Lookup(id); !ok {
+		return 0, fmt.Errorf("%w: algo:%s is not a registered algorithm", ErrBadDirective, val)
+	}
+	return id, nil
+}
+
+// randomInit gives every node a distinct ID drawn uniformly from [0, 2^numBits), so the same seed always gives the same IDs
+func randomInit(gr *g.Graph, numBits int, seed int64) error {
+	if len(gr.Nodes) > 1<<uint(numBits) {
+		return fmt.Errorf("%w: %d nodes cannot have distinct IDs below 2^%d", ErrBadDirective, len(gr.Nodes), numBits)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	used := make(map[int64]bool, len(gr.Nodes))
+	for _, node := range gr.Nodes {
+		id := rng.Int63n(1 << uint(numBits))
+		for used[id] {
+			id = rng.Int63n(1 << uint(numBits))
+		}
+		used[id] = true
+		node.Color = int(id)
+	}
+	return nil
+}
+
+// readSidecar colors a Graph from a file of name color lines, where # and % start comments.
+// Returns a *ParseError for a malformed line, a name not in the Graph, a node colored twice, or a node left uncolored
+func readSidecar(gr *g.Graph, fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	byName := make(map[string]*g.Node, len(gr.Nodes))
+	for _, node := range gr.Nodes {
+		byName[node.Name] = node
+	}
+	colored := make([]bool, len(gr.Nodes))
+	numColored := 0
+
+	scanner := newLineScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == '%' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+		}
+		color, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return &ParseError{File: fileName, Line: lineNum, Node: fields[0], Err: ErrBadLine}
+		}
+		node, ok := byName[fields[0]]
+		if !ok {
+			return &ParseError{File: fileName, Line: lineNum, Node: fields[0], Err: g.ErrUnknownNeighbor}
+		}
+		if colored[node.Ind] {
+			return &ParseError{File: fileName, Line: lineNum, Node: fields[0], Err: ErrDuplicateNode}
+		}
+		colored[node.Ind] = true
+		numColored++
+		node.Color = color
+	}
+	if err := scanner.Err(); err != nil {
+		return &ParseError{File: fileName, Line: lineNum + 1, Err: err}
+	}
+	if numColored < len(gr.Nodes) {
+		for i, ok := range colored {
+			if !ok {
+				return &ParseError{File: fileName, Node: gr.Nodes[i].Name,
+					Err: fmt.Errorf("%w: %d of %d nodes are colored", ErrMissingColor, numColored, len(gr.Nodes))}
+			}
+		}
+	}
+	return nil
+}
+
+// checkInitColoring returns ErrImproperColoring, naming the first conflict or invalid color, if gr's initial coloring is not proper
+func checkInitColoring(gr *g.Graph, init string) error {
+	report := verifyOutput(gr)
+	if report.Safe() {
+		return nil
+	}
+	if init == "" {
+		init = InitIndex
+	}
+	if len(report.Conflicts) > 0 {
+		return fmt.Errorf("%s: %w from %s: %d conflicts, e.g. %s", gr.Name, ErrImproperColoring, init, len(report.Conflicts), report.Conflicts[0])
+	}
+	return fmt.Errorf("%s: %w from %s: %d nodes have no valid color, e.g. %s", gr.Name, ErrImproperColoring, init, len(report.InvalidColors), report.InvalidColors[0])
+}
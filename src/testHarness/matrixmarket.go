@@ -0,0 +1,113 @@
+package testHarness
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+	Reading Matrix Market .mtx files, the format of the SuiteSparse Matrix Collection
+		%%MatrixMarket matrix coordinate <field> <symmetry>
+		% <comment>
+		<rows> <cols> <entries>
+		<i> <j> [value ...]
+	A square matrix is read as the graph with an edge between i and j for every nonzero entry at (i, j), whatever its value.
+	Nodes are 1-indexed and named by their number. Symmetric matrices only list their lower triangle,
+	and general ones are read as their symmetric part, so an entry and its transpose are the same edge.
+	Diagonal entries are self-loops, and are skipped.
+*/
+
+// ParseMatrixMarket takes a Matrix Market fileName and whether or not colors should be initialized to their index in the node array.
+// MaxDegree is computed from the entries, and comment lines become the Graph's Description.
+// Self-loops and entries listed twice are skipped and counted in the ParseReport.
+// Returns a *ParseError for a missing or unsupported banner, a matrix that is not square, or a malformed or out of range entry
+func ParseMatrixMarket(fileName string, colorInit bool) (g.Graph, ParseReport, error) {
+	report := ParseReport{Format: FormatMatrixMarket}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return g.Graph{}, report, err
+	}
+	defer f.Close()
+
+	scanner := newLineScanner(f)
+
+	if !scanner.Scan() {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: 1, Err: ErrBadHeader}
+	}
+	banner := strings.Fields(strings.ToLower(scanner.Text()))
+	if len(banner) != 5 || banner[0] != "%%matrixmarket" || banner[1] != "matrix" {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: 1, Err: ErrBadHeader}
+	}
+	if banner[2] != "coordinate" {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: 1,
+			Err: fmt.Errorf("%w: %s matrices have no sparsity pattern to read edges from", ErrBadHeader, banner[2])}
+	}
+
+	var comments []string
+	numNodes := -1
+	arcs := arcSet{report: &report}
+	lineNum := 1
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.HasPrefix(line, "%") {
+			comments = append(comments, strings.TrimSpace(line[1:]))
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if numNodes == -1 {
+			if len(fields) != 3 {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			rows, errRows := strconv.Atoi(fields[0])
+			cols, errCols := strconv.Atoi(fields[1])
+			if errRows != nil || errCols != nil || rows < 0 {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			if rows != cols {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum,
+					Err: fmt.Errorf("%w: a %d by %d matrix is not square", ErrBadHeader, rows, cols)}
+			}
+			numNodes = rows
+			continue
+		}
+
+		if len(fields) < 2 {
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+		}
+		i, errI := strconv.Atoi(fields[0])
+		j, errJ := strconv.Atoi(fields[1])
+		if errI != nil || errJ != nil {
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+		}
+		if i < 1 || i > numNodes {
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Node: fields[0], Err: g.ErrUnknownNeighbor}
+		}
+		if j < 1 || j > numNodes {
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Node: fields[1], Err: g.ErrUnknownNeighbor}
+		}
+		arcs.add(i-1, j-1)
+	}
+	if err := scanner.Err(); err != nil {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum + 1, Err: err}
+	}
+	if numNodes == -1 {
+		return g.Graph{}, report, &ParseError{File: fileName, Err: fmt.Errorf("%w: no size line", ErrBadHeader)}
+	}
+
+	nodeList, maxDegree := arcs.build(numberedNames(numNodes), colorInit, false)
+	name, description := describeGraph(fileName, comments, "Matrix Market")
+	return g.Graph{
+		Name:        name,
+		Description: description,
+		MaxDegree:   maxDegree,
+		Nodes:       nodeList,
+	}, report, nil
+}
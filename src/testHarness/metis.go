@@ -0,0 +1,155 @@
+package testHarness
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+	Reading the METIS .graph format used by METIS, KaHIP and the DIMACS partitioning challenge
+		% <comment>
+		<numNodes> <numEdges> [fmt [ncon]]
+		[size] [weight ...] <neighbor> [edgeWeight] <neighbor> [edgeWeight] ...
+	After the header, line i lists the neighbors of node i. Nodes are 1-indexed and named by their number.
+	fmt is up to three digits: the first says every line starts with a node size, the second that it then has ncon node weights,
+	and the third that every neighbor is followed by an edge weight. Sizes and weights are read past and ignored.
+	Every edge is listed from both of its ends.
+*/
+
+// metisFormat is the fmt field of a METIS header, saying what comes before and after the neighbors on each line
+//		hasSize: whether each line starts with the node's size
+//		numWeights: the number of node weights after the size, ncon if the weights digit is set and 0 otherwise
+//		hasEdgeWeights: whether each neighbor is followed by the weight of its edge
+type metisFormat struct {
+	hasSize bool
+	numWeights int
+	hasEdgeWeights bool
+}
+
+// parseMetisFormat parses the optional fmt and ncon fields of a METIS header
+func parseMetisFormat(fields []string) (metisFormat, error) {
+	format := metisFormat{}
+	if len(fields) == 0 {
+		return format, nil
+	}
+	digits := fields[0]
+	if len(digits) > 3 || strings.Trim(digits, "01") != "" {
+		return format, fmt.Errorf("%w: fmt %s is not up to three 0 or 1 digits", ErrBadHeader, digits)
+	}
+	digits = strings.Repeat("0", 3-len(digits)) + digits
+	format.hasSize = digits[0] == '1'
+	format.hasEdgeWeights = digits[2] == '1'
+	if digits[1] == '1' {
+		format.numWeights = 1
+		if len(fields) > 1 {
+			ncon, err := strconv.Atoi(fields[1])
+			if err != nil || ncon < 1 {
+				return format, fmt.Errorf("%w: ncon %s is not a positive integer", ErrBadHeader, fields[1])
+			}
+			format.numWeights = ncon
+		}
+	}
+	return format, nil
+}
+
+// ParseMETIS takes a METIS .graph fileName and whether or not colors should be initialized to their index in the node array.
+// MaxDegree is computed from the edges, and comment lines become the Graph's Description.
+// Self-loops, duplicate neighbors and edges listed from only one end are counted in the ParseReport, and the last are added anyway.
+// Returns a *ParseError for a malformed header or line, a neighbor out of range, or more or fewer node lines than the header gives
+func ParseMETIS(fileName string, colorInit bool) (g.Graph, ParseReport, error) {
+	report := ParseReport{Format: FormatMETIS}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return g.Graph{}, report, err
+	}
+	defer f.Close()
+
+	scanner := newLineScanner(f)
+
+	var comments []string
+	var format metisFormat
+	numNodes := -1
+	node := 0
+	arcs := arcSet{report: &report}
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.HasPrefix(line, "%") {
+			comments = append(comments, strings.TrimSpace(line[1:]))
+			continue
+		}
+		fields := strings.Fields(line)
+
+		if numNodes == -1 {
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) < 2 || len(fields) > 4 {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			numNodes, err = strconv.Atoi(fields[0])
+			if err != nil || numNodes < 0 {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: ErrBadHeader}
+			}
+			format, err = parseMetisFormat(fields[2:])
+			if err != nil {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Err: err}
+			}
+			continue
+		}
+
+		// Every line after the header is a node, even an empty one, which is a node without neighbors
+		node++
+		if node > numNodes {
+			if len(fields) == 0 {
+				continue
+			}
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum,
+				Err: fmt.Errorf("%w: more node lines than the %d nodes in the header", ErrBadLine, numNodes)}
+		}
+		skip := format.numWeights
+		if format.hasSize {
+			skip++
+		}
+		step := 1
+		if format.hasEdgeWeights {
+			step = 2
+		}
+		if len(fields) < skip || (len(fields)-skip)%step != 0 {
+			return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Node: strconv.Itoa(node), Err: ErrBadLine}
+		}
+		for i := skip; i < len(fields); i += step {
+			neighbor, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Node: strconv.Itoa(node), Err: ErrBadLine}
+			}
+			if neighbor < 1 || neighbor > numNodes {
+				return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum, Node: fields[i], Err: g.ErrUnknownNeighbor}
+			}
+			arcs.add(node-1, neighbor-1)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum + 1, Err: err}
+	}
+	if numNodes == -1 {
+		return g.Graph{}, report, &ParseError{File: fileName, Err: fmt.Errorf("%w: no header line", ErrBadHeader)}
+	}
+	if node < numNodes {
+		return g.Graph{}, report, &ParseError{File: fileName, Line: lineNum,
+			Err: fmt.Errorf("%w: %d node lines for the %d nodes in the header", ErrBadLine, node, numNodes)}
+	}
+
+	nodeList, maxDegree := arcs.build(numberedNames(numNodes), colorInit, true)
+	name, description := describeGraph(fileName, comments, "METIS")
+	return g.Graph{
+		Name:        name,
+		Description: description,
+		MaxDegree:   maxDegree,
+		Nodes:       nodeList,
+	}, report, nil
+}
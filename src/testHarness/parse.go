@@ -2,8 +2,11 @@ package testHarness
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	g "github.com/thomaseb191/go-coloring/graphs"
-	"log"
+	r "github.com/thomaseb191/go-coloring/reductions"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -12,6 +15,7 @@ import (
 /*
 	Useful functions offered by this file:
 		- ParseFile: parse a fileName to get a Graph
+		- ParseGraphFile: parse a fileName in any supported format to get a Graph (see formats.go)
 		- ParseTestFile: parses a fileName to get a list of TestDirectives
 		- ConvertStringToIntArray converts an input string and parses it into an int array
  */
@@ -19,43 +23,82 @@ import (
 // TestDirective is a struct contains a string fileName for a graph and a list of algos and any other settings
 //		GraphFile: the fileName to read in to build the graph
 //		Algos: an integer array list of IDs for algorithms to run
-//		PoolSize: the number of goroutine workers to allow (default -1)
+//		PoolSize: the most goroutine workers any algorithm may run at once (default -1 for sqrt(n))
 //		Debug: the debug level for printing and displaying test results
+//		Congest: the constant c for CONGEST mode's c*log2(n) bits per edge per round, or 0 for the LOCAL model (option congest=c)
+//		ExpectErr: the kind of error parsing GraphFile is expected to fail with, or "" if it should succeed (option expect=kind)
+//		Warmup: the number of unmeasured runs of each algorithm before timing starts (option warmup=k, default 0)
+//		Reps: the number of measured runs of each algorithm, summarized in TestData.Timing (option reps=n, default 1)
+//		Replay: whether to render a round by round replay of each algorithm to html (option replay=true, small graphs only)
+//		Sweep: the pool sizes to run each algorithm with in place of PoolSize, always including 1 (option sweep=1,2,4 or sweep=max)
+//		CSR: whether to run each algorithm on the compact g.CSR form of the graph (option csr=true)
+//		Seed: the seed every randomized algorithm draws from, or 0 to pick one from the clock when the test runs (option seed=s)
+//		Export: the format to write each algorithm's colored output in, dot, graphml or gexf, or "" for none (option export=format)
+//		Init: where the initial coloring comes from, see initColors.go, or "" for each node's index (option init=source)
+//		Topology: the topology the CV 3-coloring is told the graph has, path, cycle or forest, or "" to detect it (option topology=t)
 type TestDirective struct {
 	GraphFile string
 	Algos []int
 	PoolSize int
 	Debug int
+	Congest int
+	ExpectErr string
+	Warmup int
+	Reps int
+	Replay bool
+	Sweep []int
+	CSR bool
+	Seed int64
+	Export string
+	Init string
+	Topology string
 }
 
-// Most of parsing reference taken from // Reference from https://gobyexample.com/reading-files
+// maxLineBytes bounds a single line of a graph file. A node's line grows with its degree, so the
+// scanner's 64KB default is too small for high degree nodes such as those of a complete graph
+const maxLineBytes = 64 * 1024 * 1024
 
-// parseCheck is a helper method to log a fatal error message if an error exists
-func parseCheck(e error) {
-	if e != nil {
-		log.Fatal(e)
-	}
+// newLineScanner returns a line scanner over r that accepts lines of up to maxLineBytes
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	scanner.Split(bufio.ScanLines)
+	return scanner
 }
 
+// Most of parsing reference taken from // Reference from https://gobyexample.com/reading-files
 
 // ParseFile takes a fileName and whether or not colors should be initialized to their index in the node array.
-// Errors if file is incorrectly set up, given max degree is too small, or if directed edges are found
-func ParseFile(fileName string, colorInit bool) g.Graph {
+// A node line may give the node an initial color as Name=Color:Neighbor1,Neighbor2, which is used unless colorInit is set.
+// Returns a *ParseError if the file is incorrectly set up, the given max degree is too small, or directed edges or self-loops are found
+func ParseFile(fileName string, colorInit bool) (g.Graph, error) {
+	return parseFile(fileName, colorInit, &ParseReport{Format: FormatAdj})
+}
+
+// parseFile is ParseFile, counting the nodes given a color in report
+func parseFile(fileName string, colorInit bool, report *ParseReport) (g.Graph, error) {
 	//Initialize readers
 	f, err := os.Open(fileName)
-	parseCheck(err)
+	if err != nil {
+		return g.Graph{}, err
+	}
 
 	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	scanner.Split(bufio.ScanLines)
+	scanner := newLineScanner(f)
 
 	//Parse metadata
-	scanner.Scan()
-	var n = scanner.Text()
-	scanner.Scan()
-	var d = scanner.Text()
-	scanner.Scan()
-	deg, err := strconv.Atoi(scanner.Text())
+	var header [3]string
+	for i := range header {
+		if !scanner.Scan() {
+			return g.Graph{}, &ParseError{File: fileName, Line: i + 1, Err: ErrBadHeader}
+		}
+		header[i] = scanner.Text()
+	}
+	n, d := header[0], header[1]
+	deg, err := strconv.Atoi(strings.TrimSpace(header[2]))
+	if err != nil || deg < 0 {
+		return g.Graph{}, &ParseError{File: fileName, Line: 3, Err: ErrBadHeader}
+	}
 
 	//Initialize node tracking containers
 	var nodeList []*g.Node
@@ -66,121 +109,288 @@ func ParseFile(fileName string, colorInit bool) g.Graph {
 	var nodeNeighborNameMap map[string][]string
 	nodeNeighborNameMap = make(map[string][]string)
 
+	nodeLines := make(map[string]int)
+
 	counter := 0 //TODO: INDEX 0 OR 1
+	lineNum := 3
 
 	//Read nodes line by line
 	for scanner.Scan() {
-		splitted1 := strings.Split(strings.ReplaceAll(scanner.Text(), " ", ""), ":")
+		lineNum++
+		line := strings.ReplaceAll(scanner.Text(), " ", "")
+		if len(line) == 0 {
+			continue
+		}
+		splitted1 := strings.Split(line, ":")
+		if len(splitted1) != 2 || len(splitted1[0]) == 0 {
+			return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Err: ErrBadLine}
+		}
 
 		nodeName := splitted1[0]
-		neighborNames := []string{splitted1[1]}
-		if strings.Contains(splitted1[1], ",") {
+		color, hasColor := 0, false
+		if named := strings.SplitN(nodeName, "=", 2); len(named) == 2 {
+			conv, err := strconv.Atoi(named[1])
+			if err != nil || len(named[0]) == 0 {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: nodeName, Err: ErrBadLine}
+			}
+			nodeName, color, hasColor = named[0], conv, true
+		}
+		neighborNames := []string{}
+		if len(splitted1[1]) > 0 {
 			neighborNames = strings.Split(splitted1[1], ",")
 		}
+		for _, neighborName := range neighborNames {
+			if neighborName == nodeName {
+				return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: nodeName, Err: ErrSelfLoop}
+			}
+		}
 
 		_, ok := nodeNameMap[nodeName]
 		if ok {
-			log.Fatalf("Node %s duplicate definition", nodeName)
+			return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: nodeName, Err: ErrDuplicateNode}
 		}
 		if len(neighborNames) > deg {
-			log.Fatalf("Node %s has greater than %d degree", nodeName, deg)
+			return g.Graph{}, &ParseError{File: fileName, Line: lineNum, Node: nodeName,
+				Err: fmt.Errorf("%w: %d neighbors but max degree is %d", ErrDegreeTooLarge, len(neighborNames), deg)}
 		}
 
 		newNode := g.Node {Name: nodeName, Ind: len(nodeList)}
 		if (colorInit) {
 			newNode.Color = counter
+		} else if hasColor {
+			newNode.Color = color
+		}
+		if hasColor {
+			report.ColoredNodes++
 		}
 		nodeNameMap[nodeName] = &newNode
 		nodeNeighborNameMap[nodeName] = neighborNames
+		nodeLines[nodeName] = lineNum
 		nodeList = append(nodeList, &newNode)
 		counter++
 	}
+	if err := scanner.Err(); err != nil {
+		return g.Graph{}, &ParseError{File: fileName, Line: lineNum + 1, Err: err}
+	}
 
 	//Map string node names to their actual pointers
-	refinedNodeList := g.NodeMatch(nodeList, nodeNameMap, nodeNeighborNameMap)
+	refinedNodeList, err := g.NodeMatch(nodeList, nodeNameMap, nodeNeighborNameMap)
+	if err != nil {
+		var graphErr *g.GraphError
+		if errors.As(err, &graphErr) {
+			return g.Graph{}, &ParseError{File: fileName, Line: nodeLines[graphErr.Node], Err: err}
+		}
+		return g.Graph{}, &ParseError{File: fileName, Err: err}
+	}
 
 	return g.Graph{
 		Name: n,
 		Description: d,
 		MaxDegree: deg,
 		Nodes: refinedNodeList,
-	}
+	}, nil
 }
 
 // ParseTestFile takes a fileName and parses it to create an array of TestDirectives
-func ParseTestFile(fileName string) []TestDirective {
+// Lines containing % are comments. A malformed directive is skipped, and returned as a *ParseError with its line number
+// alongside the directives that did parse. The error is only set if the file itself cannot be read
+func ParseTestFile(fileName string) ([]TestDirective, []*ParseError, error) {
 	//Initialize readers
 	f, err := os.Open(fileName)
-	parseCheck(err)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	defer f.Close()
 	scanner := bufio.NewScanner(f)
 	scanner.Split(bufio.ScanLines)
 
 	var directiveList []TestDirective
+	var lineErrs []*ParseError
 
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		if strings.Contains(scanner.Text(), "%") {
 			continue
 		}
-		splitted1 := strings.Split(scanner.Text(), " ")
-		directiveList = append(directiveList, ParseArgsList(splitted1))
+		splitted1 := strings.Fields(scanner.Text())
+		if len(splitted1) == 0 {
+			continue
+		}
+		td, err := ParseArgsList(splitted1)
+		if err != nil {
+			lineErrs = append(lineErrs, &ParseError{File: fileName, Line: lineNum, Err: err})
+			continue
+		}
+		directiveList = append(directiveList, td)
 	}
-	return directiveList
+	return directiveList, lineErrs, scanner.Err()
 }
 
 // ParseArgsList parses an array of Strings to create a TestDirective
-func ParseArgsList(argList []string) TestDirective {
-	poolSize := -1
-	debugLevel := 3
-	var err error = nil
+// The format is graphFile [algos] [poolSize] [debug] [key=value ...], where options may be given in any order after the algos:
+//		congest=c: run in CONGEST mode with c*log2(n) bits per edge per round
+//		expect=kind: assert that parsing the graph fails with the given kind of error, e.g. expect=directed-edge
+//		warmup=k: run each algorithm k times untimed before measuring
+//		reps=n: time each algorithm n times and report min, median, mean, stddev and a 95% confidence interval
+//		replay=true: record each algorithm's coloring after every round and render it as an animated html page
+//		sweep=1,2,4,8: run each algorithm once per pool size and report its speedup and efficiency over a single worker.
+//			sweep=max uses every power of two up to GOMAXPROCS, and GOMAXPROCS itself
+//		csr=true: run each algorithm on the compact CSR form of the graph, converting only for algorithms without a CSR implementation
+//		seed=s: seed every randomized algorithm with s, to replay a run whose seed was recorded in its output
+//		export=dot: write each algorithm's colored output to ../html as Graphviz DOT, or with export=graphml or export=gexf as GraphML or GEXF
+//		init=random: start every algorithm from distinct random 62-bit IDs instead of node indices. Also init=random:b, init=file,
+//			init=sidecar, init=sidecar:fileName or init=algo:dlf, see initColors.go
+//		topology=cycle: tell the CV 3-coloring the graph is made of paths and cycles, or with topology=path or topology=forest
+//			of paths or trees, instead of detecting it
+func ParseArgsList(argList []string) (TestDirective, error) {
+	td := TestDirective{
+		GraphFile: argList[0],
+		Algos: []int{},
+		PoolSize: -1,
+		Debug: 3,
+		Reps: 1,
+	}
+	if len(argList) > 1 {
+		algos, err := ConvertStringToIntArray(argList[1])
+		if err != nil {
+			return td, err
+		}
+		td.Algos = algos
+	}
 
+	positional := 0
+	options := []string{}
 	if len(argList) > 2 {
-		poolSize, err = strconv.Atoi(argList[2])
-		if err != nil {
-			log.Fatal("Error parsing poolSize input")
+		options = argList[2:]
+	}
+	for _, arg := range options {
+		if strings.Contains(arg, "=") {
+			if err := parseOption(&td, arg); err != nil {
+				return td, err
+			}
+			continue
+		}
+		conv, err := strconv.Atoi(arg)
+		switch positional {
+		case 0:
+			if err != nil {
+				return td, fmt.Errorf("%w: poolSize %s is not an integer", ErrBadDirective, arg)
+			}
+			td.PoolSize = conv
+		case 1:
+			if err != nil {
+				return td, fmt.Errorf("%w: debug %s is not an integer", ErrBadDirective, arg)
+			}
+			td.Debug = conv
+		default:
+			return td, fmt.Errorf("%w: unexpected argument %s", ErrBadDirective, arg)
 		}
+		positional++
 	}
-	if len(argList) > 3 {
-		debugLevel, err = strconv.Atoi(argList[3])
+	return td, nil
+}
+
+// parseOption sets a single key=value option on a TestDirective
+func parseOption(td *TestDirective, option string) error {
+	splitted := strings.SplitN(option, "=", 2)
+	key, val := splitted[0], splitted[1]
+	switch key {
+	case "congest":
+		conv, err := strconv.Atoi(val)
+		if err != nil || conv < 0 {
+			return fmt.Errorf("%w: congest %s is not a non-negative integer", ErrBadDirective, val)
+		}
+		td.Congest = conv
+	case "expect":
+		if _, ok := errorKinds[val]; !ok {
+			return fmt.Errorf("%w: unknown error kind %s", ErrBadDirective, val)
+		}
+		td.ExpectErr = val
+	case "warmup":
+		conv, err := strconv.Atoi(val)
+		if err != nil || conv < 0 {
+			return fmt.Errorf("%w: warmup %s is not a non-negative integer", ErrBadDirective, val)
+		}
+		td.Warmup = conv
+	case "reps":
+		conv, err := strconv.Atoi(val)
+		if err != nil || conv < 1 {
+			return fmt.Errorf("%w: reps %s is not a positive integer", ErrBadDirective, val)
+		}
+		td.Reps = conv
+	case "replay":
+		conv, err := strconv.ParseBool(val)
 		if err != nil {
-			log.Fatal("Error parsing debug input")
+			return fmt.Errorf("%w: replay %s is not a boolean", ErrBadDirective, val)
 		}
+		td.Replay = conv
+	case "csr":
+		conv, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("%w: csr %s is not a boolean", ErrBadDirective, val)
+		}
+		td.CSR = conv
+	case "seed":
+		conv, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || conv == 0 {
+			return fmt.Errorf("%w: seed %s is not a non-zero integer", ErrBadDirective, val)
+		}
+		td.Seed = conv
+	case "export":
+		if !g.IsExportFile("." + val) {
+			return fmt.Errorf("%w: unknown export format %s", ErrBadDirective, val)
+		}
+		td.Export = val
+	case "init":
+		if err := checkInit(val); err != nil {
+			return err
+		}
+		td.Init = val
+	case "topology":
+		if !r.IsTopology(val) {
+			return fmt.Errorf("%w: unknown topology %s", ErrBadDirective, val)
+		}
+		td.Topology = val
+	case "sweep":
+		sweep, err := parseSweep(val)
+		if err != nil {
+			return err
+		}
+		td.Sweep = sweep
+	default:
+		return fmt.Errorf("%w: unknown option %s", ErrBadDirective, key)
 	}
-	return TestDirective{
-		GraphFile: argList[0],
-		Algos: ConvertStringToIntArray(argList[1]),
-		PoolSize: poolSize,
-		Debug: debugLevel,
-	}
+	return nil
 }
 
 // ConvertStringToIntArray converts an input string and parses it into an int array
-// Accepts [1,2,3]; [1,2,3,; 1,2,3]; and []
+// Accepts [1,2,3]; [1,2,3,; 1,2,3]; []; and registered short IDs such as [naive,kw]
 // Whitespace will throw an error with two many arguments
-func ConvertStringToIntArray(str string) []int {
+func ConvertStringToIntArray(str string) ([]int, error) {
 	removeBrackets1 := strings.ReplaceAll(str, "[", "")
 	removeBrackets2 := strings.ReplaceAll(removeBrackets1, "]", "")
 	if len(removeBrackets2) == 0 {
-		return []int{}
+		return []int{}, nil
 	}
 
 	splitted := strings.Split(removeBrackets2, ",")
-	if len(splitted) == 0 {
-		conv, err := strconv.Atoi(removeBrackets2)
-		if err != nil {
-			log.Fatal("Error parsing algo ID inputs")
-		}
-		return []int{conv}
-	}
 
 	var res []int
 	for _, val := range splitted {
+		if len(val) == 0 {
+			continue
+		}
 		conv, err := strconv.Atoi(val)
 		if err != nil {
-			log.Fatal("Error parsing algo ID inputs")
+			id, ok := r.LookupShortID(val)
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown algorithm %s", ErrBadDirective, val)
+			}
+			conv = id
 		}
 		res = append(res, conv)
 	}
-	return res
-}
\ No newline at end of file
+	return res, nil
+}
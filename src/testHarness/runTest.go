@@ -1,71 +1,203 @@
 package testHarness
 
 import (
+	"errors"
 	"fmt"
 	r "github.com/thomaseb191/go-coloring/reductions"
 	//d "../display" //TODO: IMPORT
 	g "github.com/thomaseb191/go-coloring/graphs"
+	"runtime"
 	"time"
 )
 
 // TestData is a struct to handle metadata and an output graph
 //		Name: the name of the test, following the convention of graphName_algorithmName
-//		DurationMillis: the Duration of the reduction, designed to be converted to millis in post-processing
-//		Output: the graph produced by the output of the algorithm
+//		AlgoID: the registered ID of the algorithm that was run
+//		DurationMillis: the mean Duration of the measured repetitions, designed to be converted to millis in post-processing
+//		Output: the graph produced by the output of the algorithm in its last repetition, or in its worst one if an earlier repetition
+//			was unsafe or over MaxDegree+1 colors
 //		NumColors: the number of colors in the output graph. Its correctness should be asserted in post-processing
-//		IsSafe: the result of running g.IsSafe() on the output
+//		IsSafe: whether the output is a proper coloring, as found by g.Verify()
+//		Report: the full g.Verify() report on the output, with every conflict and whether it stayed within MaxDegree+1 colors
+//		BadReps: the number of measured repetitions whose output was unsafe or over MaxDegree+1 colors. Every repetition is verified
+//		Stats: the LOCAL-model rounds and messages the algorithm needed in the repetition of Output
+//		Timing: the min, median, mean, stddev and 95% confidence interval over every measured repetition
+//		Speedup, Efficiency: in a pool size sweep, the single-worker mean runtime over this run's, and that speedup per worker. 0 otherwise
+//		Seed: the seed the algorithm drew its random choices from. Running the directive again with seed=Seed reproduces it
+//		InitColors, InitMaxColor: the number of distinct colors in the initial coloring, and the largest of them
 type TestData struct {
 	Name string
+	AlgoID int
 	DurationMillis time.Duration
 	Output g.Graph
 	NumColors int
 	IsSafe bool
+	Report g.VerifyReport
+	BadReps int
+	Stats r.RunStats
+	Timing Timing
+	Speedup float64
+	Efficiency float64
+	Seed int64
+	InitColors int
+	InitMaxColor int
 }
 
-// RunTest runs any number of color-reducing algorithms on a given graph file.
-// 		fileName: the string name of the file for the graph
-// 		algos: an array of IDs mapping to algorithm
-// 		poolSize: the number of worker goroutines allowed for parallel algorithms
-// 		debug: 0 if just generate output, 1 if allow prints, 2 if just graph and output, 3 if allow graph and prints
-func RunTest(fileName string, algos []int, poolSize int, debug int) []TestData {
+// RunTest runs any number of color-reducing algorithms on the graph file of a TestDirective.
+// 		GraphFile: the string name of the file for the graph
+// 		Algos: an array of IDs mapping to algorithm
+// 		PoolSize: the most worker goroutines any algorithm may run at once, or <= 0 for sqrt(n)
+// 		Debug: 0 if just generate output, 1 if allow prints, 2 if just graph and output, 3 if allow graph and prints
+// 		Congest: the CONGEST bandwidth constant, or 0 for the LOCAL model
+// 		ExpectErr: if set, only the graph and its initial coloring are read, and RunTest succeeds exactly when that fails with that kind of error
+// 		Warmup, Reps: every algorithm runs Warmup times untimed, then Reps times timed, each on a fresh copy of the graph
+// 		Replay: if set, every algorithm runs once more untimed with a Recorder, and its snapshots are rendered to html
+// 		Sweep: if set, every algorithm is run once per pool size instead of with PoolSize, see sweep.go
+// 		CSR: if set, every algorithm runs on a g.CSR copy of the graph, natively if it has the NativeCSR capability
+// 		Seed: the seed of every algorithm's every run, including warmups and replays. If 0, one is picked from the clock
+// 		Export: if set, every algorithm's output is written to ../html in that format, see g.WriteGraphFile
+// 		Topology: the topology the CV 3-coloring is told the graph has, or "" to detect it
+// 		Init: where the initial coloring comes from, see initColors.go. It must be proper, or RunTest fails with ErrImproperColoring
+// Returns an error instead of results if the graph or its initial coloring cannot be read, or an algorithm ID is not registered
+func RunTest(td TestDirective) ([]TestData, error) {
+	fileName, algos, debug := td.GraphFile, td.Algos, td.Debug
+	if td.Seed == 0 {
+		td.Seed = time.Now().UnixNano()
+	}
 	//Parse and build the graph. Initialize the colors manually after asserting not safe
-	var testDatas []TestData
-	initGraph := ParseFile(fileName, false)
-	if debug % 2 == 1 {
-		fmt.Printf("Initial IsSafe() for %s without color init: %t\n", initGraph.Name, g.IsSafe(&initGraph))
+	initGraph, parseReport, err := ParseGraphFileWithReport(fileName, false)
+	if err == nil {
+		if !parseReport.Clean() {
+			fmt.Printf("Read %s as %s\n", initGraph.Name, parseReport)
+		}
+		if debug % 2 == 1 && td.ExpectErr == "" {
+			fmt.Printf("Initial IsSafe() for %s without color init: %t\n", initGraph.Name, g.IsSafe(&initGraph))
+		}
+		_, graphFile := SplitFormat(fileName)
+		err = applyInit(&initGraph, td, graphFile, parseReport)
+		if err == nil {
+			err = checkInitColoring(&initGraph, td.Init)
+		}
+	}
+	if td.ExpectErr != "" {
+		return nil, checkExpectedError(td, err)
+	}
+	if err != nil {
+		return nil, err
 	}
-	g.RunColorInit(&initGraph)
 
 
 	if len(algos) == 0 {
 		algos = r.AllAlgIds
 	}
+	if debug % 2 == 1 {
+		fmt.Printf("Seed for %s: %d\n", initGraph.Name, td.Seed)
+		if td.Init != "" {
+			fmt.Printf("Initial coloring of %s from %s: %d colors, largest %d\n", initGraph.Name, td.Init, g.CountColors(&initGraph), maxColor(&initGraph))
+		}
+	}
+	if len(td.Sweep) > 0 {
+		return runSweep(initGraph, algos, td)
+	}
+	return runAlgorithms(initGraph, algos, td)
+}
+
+// runAlgorithms runs every algorithm in algos on copies of initGraph, whose colors are already initialized, with the settings of td
+func runAlgorithms(initGraph g.Graph, algos []int, td TestDirective) ([]TestData, error) {
+	debug := td.Debug
+	opts := r.RunOptions{PoolSize: td.PoolSize, Debug: debug, Congest: td.Congest, Seed: td.Seed, Topology: td.Topology}
+	var testDatas []TestData
+
+	reps := td.Reps
+	if reps < 1 {
+		reps = 1
+	}
+
+	initColors, initMaxColor := g.CountColors(&initGraph), maxColor(&initGraph)
+
+	var initCSR *g.CSR
+	if td.CSR {
+		initCSR = g.ToCSR(&initGraph)
+	}
 
 	//Start the time, run algorithms
 	for _, algo := range algos {
-		copiedGraph := g.DeepCopy(&initGraph)
-		start := time.Now()
-		outGraph, algoName := r.RunReduction(copiedGraph, algo, poolSize, debug)
+		//Warm up without timing, so caches, the scheduler and the heap settle before measuring
+		for w := 0; w < td.Warmup; w++ {
+			if _, _, _, _, err := timedRun(&initGraph, initCSR, algo, opts); err != nil {
+				return testDatas, err
+			}
+		}
 
-		//Stop the time, check the algorithm
-		elapsed := time.Since(start)
-		//fmt.Println(start, time.Now(), elapsed.Milliseconds(), elapsed.Nanoseconds())
-		numColors := g.CountColors(&outGraph)
-		isSafe := g.IsSafe(&outGraph)
+		var outGraph g.Graph
+		var algoName string
+		var stats r.RunStats
+		var report g.VerifyReport
+		badReps := 0
+		durations := make([]time.Duration, 0, reps)
+		for rep := 0; rep < reps; rep++ {
+			repGraph, repName, repStats, elapsed, err := timedRun(&initGraph, initCSR, algo, opts)
+			if err != nil {
+				return testDatas, err
+			}
+			durations = append(durations, elapsed)
+
+			//Verify every rep, keeping the last one unless an earlier one was worse, so a bad rep is never hidden by later ones
+			repReport := verifyOutput(&repGraph)
+			if repReport.Status() != g.StatusSafe {
+				badReps++
+			}
+			if rep == 0 || statusRank(repReport) >= statusRank(report) {
+				outGraph, algoName, stats, report = repGraph, repName, repStats, repReport
+			}
+		}
+
+		if td.Replay {
+			if err := renderReplay(&initGraph, algo, opts); err != nil {
+				return testDatas, err
+			}
+		}
+
+		//Check the algorithm
+		timing := summarizeDurations(durations, td.Warmup)
+		elapsed := time.Duration(timing.Mean)
 
 		if debug % 2 == 1 {
-			fmt.Printf("Output IsSafe() for %s_%s in %d: %t\n", initGraph.Name, algoName, elapsed.Nanoseconds(), isSafe)
-			fmt.Printf("\t\tNum Colors: %d\n", numColors)
+			fmt.Printf("Output IsSafe() for %s_%s in %d: %t\n", initGraph.Name, algoName, elapsed.Nanoseconds(), report.Safe())
+			fmt.Printf("\t\tNum Colors: %d\n", report.NumColors)
+			printReport(report)
+			if badReps > 0 {
+				fmt.Printf("\t\t%d of %d reps were not safe, reporting the worst\n", badReps, reps)
+			}
+			if timing.Reps() > 1 {
+				fmt.Printf("\t\tReps: %d\tMin: %d\tMedian: %d\tStdDev: %d\t95%% CI: [%d, %d]\n", timing.Reps(), timing.Min, timing.Median, timing.StdDev, timing.CILow, timing.CIHigh)
+			}
+			fmt.Printf("\t\tRounds: %d\tMessages: %d\tMax Node Messages: %d\n", stats.Rounds, stats.Messages, stats.MaxNodeMessages)
+			if stats.BitCap > 0 {
+				fmt.Printf("\t\tCONGEST compliant: %t\tOversized Messages: %d\tCONGEST Rounds: %d\n", stats.CongestCompliant(), stats.OversizedMessages, stats.CongestRounds)
+			}
 		}
 		testName := initGraph.Name + "_" + algoName
-
+		if td.Export != "" {
+			if err := g.WriteGraphFile(&outGraph, fmt.Sprintf("../html/%s.%s", testName, td.Export)); err != nil {
+				return testDatas, err
+			}
+		}
 
 		newTest := TestData{
 			Name: testName,
+			AlgoID: algo,
 			DurationMillis: elapsed,
 			Output: outGraph,
-			NumColors: numColors,
-			IsSafe: isSafe,
+			NumColors: report.NumColors,
+			IsSafe: report.Safe(),
+			Report: report,
+			BadReps: badReps,
+			Stats: stats,
+			Timing: timing,
+			Seed: td.Seed,
+			InitColors: initColors,
+			InitMaxColor: initMaxColor,
 		}
 		testDatas = append(testDatas, newTest)
 
@@ -88,5 +220,119 @@ func RunTest(fileName string, algos []int, poolSize int, debug int) []TestData {
 			//g.GenerateHTMLForOne(&outGraph, testName)
 		}
 	}
-	return testDatas
+	return testDatas, nil
+}
+
+// maxColor returns the largest color of any node in gr, or -1 if it has none
+func maxColor(gr *g.Graph) int {
+	largest := -1
+	for _, node := range gr.Nodes {
+		if node.Color > largest {
+			largest = node.Color
+		}
+	}
+	return largest
+}
+
+// timedRun runs an algorithm once on a fresh copy of the graph, timing only the algorithm itself.
+// If initCSR is set the copy is a CSR, and the output is converted back to a Graph once the time is stopped
+func timedRun(initGraph *g.Graph, initCSR *g.CSR, algo int, opts r.RunOptions) (g.Graph, string, r.RunStats, time.Duration, error) {
+	if initCSR != nil {
+		copied := initCSR.Copy()
+		runtime.GC()
+		start := time.Now()
+		algoName, stats, err := r.RunReductionCSR(copied, algo, opts)
+		elapsed := time.Since(start)
+		if err != nil {
+			return g.Graph{}, "", stats, elapsed, err
+		}
+		return copied.ToGraph(), algoName, stats, elapsed, nil
+	}
+	copiedGraph := g.DeepCopy(initGraph)
+	//Collect garbage from earlier runs so it is not charged to this one
+	runtime.GC()
+	start := time.Now()
+	outGraph, algoName, stats, err := r.RunReduction(copiedGraph, algo, opts)
+	//Stop the time
+	return outGraph, algoName, stats, time.Since(start), err
+}
+
+// parallelVerifyNodes is the graph size from which outputs are verified on every CPU with g.VerifyParallel
+const parallelVerifyNodes = 50000
+
+// verifyOutput verifies an algorithm's output, in parallel for large graphs. Both give identical reports
+func verifyOutput(gr *g.Graph) g.VerifyReport {
+	if len(gr.Nodes) >= parallelVerifyNodes {
+		return g.VerifyParallel(gr, runtime.GOMAXPROCS(0))
+	}
+	return g.Verify(gr)
+}
+
+// statusRank orders reports from best to worst: safe, then too many colors, then unsafe
+func statusRank(report g.VerifyReport) int {
+	switch report.Status() {
+	case g.StatusSafe:
+		return 0
+	case g.StatusTooManyColors:
+		return 1
+	}
+	return 2
+}
+
+// maxReportedConflicts bounds the conflicts printed for a single output, since a broken algorithm may produce thousands
+const maxReportedConflicts = 10
+
+// printReport prints what is wrong with a coloring, if anything, at the verbosity of debug output
+func printReport(report g.VerifyReport) {
+	fmt.Printf("\t\tStatus: %s\tColors: %d of %d allowed\n", report.Status(), report.NumColors, report.ColorBound)
+	if !report.DegreeMatches {
+		fmt.Printf("\t\tDeclared MaxDegree %d does not match the actual max degree %d\n", report.DeclaredMaxDegree, report.ActualMaxDegree)
+	}
+	if len(report.InvalidColors) > 0 {
+		fmt.Printf("\t\t%d nodes have no valid color, e.g. %s\n", len(report.InvalidColors), report.InvalidColors[0])
+	}
+	for i, conflict := range report.Conflicts {
+		if i == maxReportedConflicts {
+			fmt.Printf("\t\t... and %d more conflicts\n", len(report.Conflicts)-maxReportedConflicts)
+			break
+		}
+		fmt.Printf("\t\tConflict: %s\n", conflict)
+	}
+}
+
+// replayMaxNodes is the largest graph a replay is rendered for, since every frame copies every color and draws every node
+const replayMaxNodes = 500
+
+// replayMaxSnapshots bounds the number of frames of a replay
+const replayMaxSnapshots = 2000
+
+// renderReplay runs an algorithm on a copy of initGraph while recording its coloring after every round,
+// then renders the recording as an animated html page. Graphs over replayMaxNodes nodes are skipped
+func renderReplay(initGraph *g.Graph, algo int, opts r.RunOptions) error {
+	if len(initGraph.Nodes) > replayMaxNodes {
+		fmt.Printf("Skipping replay of %s: %d nodes is over the limit of %d\n", initGraph.Name, len(initGraph.Nodes), replayMaxNodes)
+		return nil
+	}
+	recorder := &r.Recorder{MaxSnapshots: replayMaxSnapshots}
+	opts.Recorder = recorder
+	_, algoName, _, err := r.RunReduction(g.DeepCopy(initGraph), algo, opts)
+	if err != nil {
+		return err
+	}
+	g.GenerateHTMLForReplay(initGraph, recorder.Snapshots, initGraph.Name+"_"+algoName)
+	return nil
+}
+
+// checkExpectedError returns nil if err is of the kind named by td.ExpectErr, and an error describing the mismatch otherwise
+func checkExpectedError(td TestDirective, err error) error {
+	if err == nil {
+		return fmt.Errorf("%s: %w: wanted %s", td.GraphFile, ErrUnexpectedSuccess, td.ExpectErr)
+	}
+	if !errors.Is(err, errorKinds[td.ExpectErr]) {
+		return fmt.Errorf("wanted %s, got: %w", td.ExpectErr, err)
+	}
+	if td.Debug % 2 == 1 {
+		fmt.Printf("Got expected error %s: %s\n", td.ExpectErr, err)
+	}
+	return nil
 }
\ No newline at end of file
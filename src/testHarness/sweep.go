@@ -0,0 +1,88 @@
+package testHarness
+
+import (
+	"fmt"
+	g "github.com/thomaseb191/go-coloring/graphs"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	Pool size sweeps
+		- runSweep: runs every algorithm of a directive once per pool size, recording its speedup and efficiency
+	Speedup is an algorithm's mean runtime with a single worker over its mean runtime with p workers, and efficiency is speedup / p,
+	where p is the number of workers the run actually got. Pools never get more workers than the graph has nodes.
+*/
+
+// parseSweep parses the value of a sweep option: a comma separated list of positive pool sizes, or max.
+// The sizes are returned sorted without duplicates, and 1 is added if missing since every speedup is measured against it
+func parseSweep(val string) ([]int, error) {
+	if val == "max" {
+		return maxSweep(runtime.GOMAXPROCS(0)), nil
+	}
+	seen := map[int]bool{1: true}
+	sweep := []int{1}
+	for _, field := range strings.Split(val, ",") {
+		if len(field) == 0 {
+			continue
+		}
+		conv, err := strconv.Atoi(field)
+		if err != nil || conv < 1 {
+			return nil, fmt.Errorf("%w: sweep pool size %s is not a positive integer", ErrBadDirective, field)
+		}
+		if !seen[conv] {
+			seen[conv] = true
+			sweep = append(sweep, conv)
+		}
+	}
+	sort.Ints(sweep)
+	return sweep, nil
+}
+
+// maxSweep returns every power of two below maxProcs, followed by maxProcs itself
+func maxSweep(maxProcs int) []int {
+	sweep := []int{1}
+	for p := 2; p < maxProcs; p *= 2 {
+		sweep = append(sweep, p)
+	}
+	if maxProcs > 1 {
+		sweep = append(sweep, maxProcs)
+	}
+	return sweep
+}
+
+// runSweep runs every algorithm in algos on copies of initGraph once per pool size in td.Sweep, smallest first.
+// Every result is named with its worker count, e.g. Graph_N1000_D5_Cole-Vishkin_W4, and carries its speedup and efficiency.
+// A replay, if asked for, is only rendered for the first pool size, since the coloring does not depend on it
+func runSweep(initGraph g.Graph, algos []int, td TestDirective) ([]TestData, error) {
+	var testDatas []TestData
+	baseline := make(map[int]int64)
+	for i, poolSize := range td.Sweep {
+		run := td
+		run.PoolSize = poolSize
+		run.Replay = td.Replay && i == 0
+		results, err := runAlgorithms(initGraph, algos, run)
+		if err != nil {
+			return testDatas, err
+		}
+		for j := range results {
+			res := &results[j]
+			elapsed := res.DurationMillis.Nanoseconds()
+			if poolSize == 1 {
+				baseline[res.AlgoID] = elapsed
+			}
+			if base, ok := baseline[res.AlgoID]; ok && elapsed > 0 {
+				res.Speedup = float64(base) / float64(elapsed)
+				res.Efficiency = res.Speedup / float64(res.Stats.Workers)
+			}
+			res.Name = fmt.Sprintf("%s_W%d", res.Name, res.Stats.Workers)
+			if td.Debug%2 == 1 {
+				fmt.Printf("\t\tWorkers: %d\tSpeedup: %.2f\tEfficiency: %.2f\n", res.Stats.Workers, res.Speedup, res.Efficiency)
+			}
+		}
+		testDatas = append(testDatas, results...)
+	}
+	return testDatas, nil
+}
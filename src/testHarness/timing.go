@@ -0,0 +1,93 @@
+package testHarness
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+/*
+	Summary statistics for repeated timings of one algorithm on one graph.
+	The 95% confidence interval is for the mean, using Student's t distribution since repetitions are usually few.
+*/
+
+// Timing summarizes the measured repetitions of one algorithm on one graph. All times are in nanoseconds
+//		Warmup: the number of unmeasured runs made before the repetitions
+//		Samples: the duration of each measured repetition, in the order they ran
+//		Min, Median, Mean, StdDev: the usual statistics over Samples. StdDev is the sample standard deviation
+//		CILow, CIHigh: the bounds of the 95% confidence interval for the mean, equal to Mean with a single repetition
+type Timing struct {
+	Warmup  int
+	Samples []int64
+	Min     int64
+	Median  int64
+	Mean    int64
+	StdDev  int64
+	CILow   int64
+	CIHigh  int64
+}
+
+// tTable975 holds the 0.975 quantile of Student's t distribution for 1 through 30 degrees of freedom
+var tTable975 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical95 returns the two-sided 95% critical value of Student's t distribution with df degrees of freedom.
+// Beyond the table it falls back to the normal distribution's 1.96
+func tCritical95(df int) float64 {
+	if df < 1 {
+		return 0
+	}
+	if df <= len(tTable975) {
+		return tTable975[df-1]
+	}
+	return 1.96
+}
+
+// Reps returns the number of measured repetitions
+func (t Timing) Reps() int {
+	return len(t.Samples)
+}
+
+// summarizeDurations computes the Timing of a non-empty list of measured durations
+func summarizeDurations(durations []time.Duration, warmup int) Timing {
+	n := len(durations)
+	samples := make([]int64, n)
+	sorted := make([]int64, n)
+	var sum float64
+	for i, d := range durations {
+		samples[i] = d.Nanoseconds()
+		sorted[i] = samples[i]
+		sum += float64(samples[i])
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	mean := sum / float64(n)
+
+	var stdDev float64
+	if n > 1 {
+		var squares float64
+		for _, s := range samples {
+			squares += (float64(s) - mean) * (float64(s) - mean)
+		}
+		stdDev = math.Sqrt(squares / float64(n-1))
+	}
+	halfWidth := tCritical95(n-1) * stdDev / math.Sqrt(float64(n))
+
+	return Timing{
+		Warmup:  warmup,
+		Samples: samples,
+		Min:     sorted[0],
+		Median:  median,
+		Mean:    int64(math.Round(mean)),
+		StdDev:  int64(math.Round(stdDev)),
+		CILow:   int64(math.Round(mean - halfWidth)),
+		CIHigh:  int64(math.Round(mean + halfWidth)),
+	}
+}